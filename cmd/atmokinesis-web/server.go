@@ -1,22 +1,33 @@
 package atmokinesis_web
 
 import (
+	"encoding/json"
 	"errors"
 	"github.com/gorilla/websocket"
 	"github.com/insubordination/atmokinesis/cmd/atmokinesis/scheduler"
+	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 	"time"
 )
 
+const (
+	defaultPreviewCount = 5
+	maxPreviewCount     = 100
+)
+
+// Port is the port StartServer serves the web API on.
+const Port = 8082
+
 func StartServer() (err error) {
 	var upgrader = &websocket.Upgrader{}
 	mux := http.NewServeMux()
 	Routes(upgrader, mux)
 	go func() {
-		serveErr := http.ListenAndServe(":8082", mux)
+		serveErr := http.ListenAndServe(":"+strconv.Itoa(Port), mux)
 		if serveErr != nil {
-			errors.As(serveErr, &err)
+			err = serveErr
 		}
 	}()
 	return
@@ -27,6 +38,12 @@ func Routes(upgrader *websocket.Upgrader, mux *http.ServeMux) {
 
 	})
 
+	mux.HandleFunc("/tasks/trigger", triggerTask)
+	mux.HandleFunc("/tasks/pause", taskAction(scheduler.PauseTask))
+	mux.HandleFunc("/tasks/resume", taskAction(scheduler.ResumeTask))
+	mux.HandleFunc("/tasks/schedule", scheduleOnce)
+	mux.HandleFunc("/schedule/preview", schedulePreview)
+
 	mux.HandleFunc("/taskstatus", func(writer http.ResponseWriter, request *http.Request) {
 		tic := time.NewTicker(3000 * time.Millisecond)
 		upgrader.CheckOrigin = func(r *http.Request) bool {
@@ -73,3 +90,155 @@ func Routes(upgrader *websocket.Upgrader, mux *http.ServeMux) {
 		}()
 	})
 }
+
+// taskAction handles a POST naming a task through the "id" query parameter and
+// applies fn to it.
+func taskAction(fn func(id scheduler.ID) error) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		if request.Method != http.MethodPost {
+			http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id := request.URL.Query().Get("id")
+		if id == "" {
+			http.Error(writer, "missing task id", http.StatusBadRequest)
+			return
+		}
+		if err := fn(scheduler.ID(id)); err != nil {
+			if errors.Is(err, scheduler.ErrTaskNotFound) {
+				http.Error(writer, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writer.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// triggerTask handles a POST that runs the task named by the "id" query
+// parameter now. A JSON object body of strings, such as {"region": "eu"},
+// overrides the task's default parameters.
+func triggerTask(writer http.ResponseWriter, request *http.Request) {
+	var params scheduler.Params
+	if err := json.NewDecoder(request.Body).Decode(&params); err != nil && err != io.EOF {
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		http.Error(writer, "bad params: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	taskAction(func(id scheduler.ID) error {
+		return scheduler.TriggerTaskWithParams(id, params)
+	})(writer, request)
+}
+
+// scheduleOnce handles a POST that runs the task named by the "id" query
+// parameter a single time, either at the RFC 3339 time in the "at" query
+// parameter or once the duration in the "after" query parameter has passed.
+// It answers with the ID of the new one-shot entry.
+func scheduleOnce(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Access-Control-Allow-Origin", "*")
+	if request.Method != http.MethodPost {
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := request.URL.Query()
+	id := query.Get("id")
+	if id == "" {
+		http.Error(writer, "missing task id", http.StatusBadRequest)
+		return
+	}
+
+	var (
+		at  time.Time
+		err error
+	)
+	switch {
+	case query.Get("at") != "":
+		at, err = time.Parse(time.RFC3339, query.Get("at"))
+	case query.Get("after") != "":
+		var after time.Duration
+		after, err = time.ParseDuration(query.Get("after"))
+		at = time.Now().Add(after)
+	default:
+		err = errors.New("missing at or after")
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	onceID, err := scheduler.ScheduleTaskOnce(scheduler.ID(id), at)
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrTaskNotFound):
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, scheduler.ErrNotInFuture):
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, scheduler.ErrEntryExists):
+			http.Error(writer, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(writer).Encode(map[string]scheduler.ID{"id": onceID})
+}
+
+// schedulePreview answers a GET with the next run times of either the task
+// named by the "id" query parameter or the "cron" query parameter, worked out
+// in the "tz" time zone. The "n" query parameter sets how many are returned.
+func schedulePreview(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Access-Control-Allow-Origin", "*")
+	if request.Method != http.MethodGet {
+		http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := request.URL.Query()
+	n := defaultPreviewCount
+	if count := query.Get("n"); count != "" {
+		var err error
+		if n, err = strconv.Atoi(count); err != nil || n < 1 || n > maxPreviewCount {
+			http.Error(writer, "n must be a number between 1 and "+strconv.Itoa(maxPreviewCount), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var (
+		times []time.Time
+		err   error
+	)
+	switch {
+	case query.Get("id") != "":
+		times, err = scheduler.PreviewTask(scheduler.ID(query.Get("id")), n)
+		if errors.Is(err, scheduler.ErrTaskNotFound) {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+	case query.Get("cron") != "":
+		loc := time.Local
+		if tz := query.Get("tz"); tz != "" {
+			if loc, err = time.LoadLocation(tz); err != nil {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		times, err = scheduler.NextActivations(scheduler.Cron(query.Get("cron")), n, time.Now(), loc, nil)
+	default:
+		http.Error(writer, "missing task id or cron", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(writer).Encode(times); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/insubordination/atmokinesis/cmd/atmokinesis/scheduler"
+	"os"
+	"time"
+)
+
+// preview prints the next run times of a cron string, for example
+//
+//	atmokinesis preview -n 10 -tz Europe/Berlin "0 9 * * 1-5"
+//
+// and returns the exit code.
+func preview(args []string) int {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	n := flags.Int("n", 5, "Number of run times to show.")
+	tz := flags.String("tz", "Local", "Time zone to work out the run times in.")
+	start := flags.String("start", "", "Start date of the schedule, RFC 3339.")
+	end := flags.String("end", "", "End date of the schedule, RFC 3339.")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: atmokinesis preview [flags] <cron>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	var startDate, endDate time.Time
+	for _, date := range []struct {
+		value string
+		into  *time.Time
+	}{{*start, &startDate}, {*end, &endDate}} {
+		if date.value == "" {
+			continue
+		}
+		if *date.into, err = time.ParseInLocation(time.RFC3339, date.value, loc); err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return 1
+		}
+	}
+
+	opts := scheduler.NewScheduleOptions(startDate, endDate, false, false, false)
+	times, err := scheduler.NextActivations(scheduler.Cron(flags.Arg(0)), *n, time.Now(), loc, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	for _, t := range times {
+		fmt.Println(t.Format(time.RFC3339))
+	}
+	return 0
+}
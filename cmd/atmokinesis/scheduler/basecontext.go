@@ -3,11 +3,13 @@ package scheduler
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"sync"
 	"time"
 )
 
 type BaseContext struct {
+	context.Context
 	executionDate   time.Time
 	startDate       time.Time
 	nextRunDate     time.Time
@@ -15,6 +17,12 @@ type BaseContext struct {
 	notifySubTasks  chan bool
 	subTaskStream   chan interface{}
 	logWriteSyncer  WriteSyncer
+	outputs         Outputs
+	parentOutputs   Outputs
+	previousOutputs Outputs
+	item            *mappedSubTask
+	mapped          *[]mappedSubTask
+	params          Params
 	*sync.RWMutex
 }
 
@@ -42,17 +50,20 @@ func (bws *BaseWriteSyncer) Write(p []byte) (n int, err error) {
 }
 
 func (bws *BaseWriteSyncer) Sync() error {
+	bws.Lock()
+	defer bws.Unlock()
 	bws.Writer.Flush()
 	return nil
 }
 
-func NewBaseContext(executionDate time.Time, startDate time.Time, nextRunDate time.Time, previousRunDate time.Time, subTaskStream chan interface{}, syncer WriteSyncer) (Context, chan bool, chan interface{}) {
+func NewBaseContext(ctx context.Context, executionDate time.Time, startDate time.Time, nextRunDate time.Time, previousRunDate time.Time, subTaskStream chan interface{}, syncer WriteSyncer) (Context, chan bool, chan interface{}) {
 	var notifySubTasks = make(chan bool, 1)
 
 	if subTaskStream == nil {
 		subTaskStream = make(chan interface{}, 100)
 	}
 	return &BaseContext{
+		Context:         ctx,
 		executionDate:   executionDate,
 		startDate:       startDate,
 		nextRunDate:     nextRunDate,
@@ -60,6 +71,8 @@ func NewBaseContext(executionDate time.Time, startDate time.Time, nextRunDate ti
 		notifySubTasks:  notifySubTasks,
 		subTaskStream:   subTaskStream,
 		logWriteSyncer:  syncer,
+		outputs:         make(Outputs),
+		mapped:          new([]mappedSubTask),
 		RWMutex:         new(sync.RWMutex),
 	}, notifySubTasks, subTaskStream
 }
@@ -80,8 +93,13 @@ func (b BaseContext) PreviousRunDate() time.Time {
 	return b.previousRunDate
 }
 
+// NotifySubTasks signals that the run's sub-tasks may start. They start once
+// the run has returned either way, so calling it more than once doesn't block.
 func (b BaseContext) NotifySubTasks() {
-	b.notifySubTasks <- true
+	select {
+	case b.notifySubTasks <- true:
+	default:
+	}
 }
 
 func (b BaseContext) StreamToSubTasks(out interface{}) {
@@ -91,3 +109,56 @@ func (b BaseContext) StreamToSubTasks(out interface{}) {
 func (b BaseContext) LogWriteSyncer() WriteSyncer {
 	return b.logWriteSyncer
 }
+
+func (b BaseContext) SetOutput(name string, value interface{}) error {
+	b.Lock()
+	defer b.Unlock()
+	return b.outputs.set(name, value)
+}
+
+func (b BaseContext) Outputs() Outputs {
+	b.RLock()
+	defer b.RUnlock()
+	if len(b.outputs) == 0 {
+		return nil
+	}
+	outputs := make(Outputs, len(b.outputs))
+	for name, raw := range b.outputs {
+		outputs[name] = raw
+	}
+	return outputs
+}
+
+func (b BaseContext) ParentOutputs() Outputs {
+	return b.parentOutputs
+}
+
+func (b BaseContext) PreviousOutputs() Outputs {
+	return b.previousOutputs
+}
+
+func (b BaseContext) MapSubTask(template Task, keys ...string) {
+	b.Lock()
+	defer b.Unlock()
+	for i, key := range keys {
+		*b.mapped = append(*b.mapped, mappedSubTask{template: template, index: i, key: key})
+	}
+}
+
+func (b BaseContext) MapIndex() int {
+	if b.item == nil {
+		return -1
+	}
+	return b.item.index
+}
+
+func (b BaseContext) MapKey() string {
+	if b.item == nil {
+		return ""
+	}
+	return b.item.key
+}
+
+func (b BaseContext) Params() Params {
+	return b.params
+}
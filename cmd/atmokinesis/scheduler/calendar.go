@@ -0,0 +1,322 @@
+package scheduler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Calendar is a named set of days, such as exchange holidays or change-freeze
+// windows, on which tasks that use it must not run.
+type Calendar struct {
+	Name string
+	days map[string]bool
+}
+
+// NewCalendar returns a calendar holding the days of the given times.
+func NewCalendar(name string, days ...time.Time) *Calendar {
+	c := &Calendar{Name: name, days: make(map[string]bool)}
+	for _, day := range days {
+		c.Add(day)
+	}
+	return c
+}
+
+// Add adds the day of t, in t's location, to the calendar.
+func (c *Calendar) Add(t time.Time) {
+	c.days[t.Format(dateLayout)] = true
+}
+
+// Contains returns true if the day of t, in t's location, is in the calendar.
+func (c *Calendar) Contains(t time.Time) bool {
+	return c.days[t.Format(dateLayout)]
+}
+
+var (
+	calendarsMu sync.RWMutex
+	calendars   = make(map[string]*Calendar)
+)
+
+// RegisterCalendar makes the calendar available to tasks under its name,
+// replacing any calendar registered under the same name.
+func RegisterCalendar(c *Calendar) {
+	calendarsMu.Lock()
+	defer calendarsMu.Unlock()
+	calendars[c.Name] = c
+}
+
+// lookupCalendar returns the calendar registered under name, or nil.
+func lookupCalendar(name string) *Calendar {
+	calendarsMu.RLock()
+	defer calendarsMu.RUnlock()
+	return calendars[name]
+}
+
+// LoadCalendarFile reads a calendar from an iCalendar (.ics) file, taking the
+// days of every event in it, or from a date list file, and registers it under
+// name.
+//
+// A date list holds one day per line as 2006-01-02, or an inclusive range of
+// days as 2006-12-24..2006-12-31. Anything after the first field of a line,
+// blank lines and lines starting with '#' are ignored.
+func LoadCalendarFile(name, path string) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := NewCalendar(name)
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		err = readICS(c, f)
+	} else {
+		err = readDateList(c, f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar %s from %s: %w", name, path, err)
+	}
+	RegisterCalendar(c)
+	return c, nil
+}
+
+// readDateList adds the days of a date list to c.
+func readDateList(c *Calendar, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		startAndEnd := strings.Split(fields[0], "..")
+		if len(startAndEnd) > 2 {
+			return fmt.Errorf("line %d: too many ranges: %s", n, fields[0])
+		}
+		start, err := time.Parse(dateLayout, startAndEnd[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+		end := start
+		if len(startAndEnd) == 2 {
+			if end, err = time.Parse(dateLayout, startAndEnd[1]); err != nil {
+				return fmt.Errorf("line %d: %w", n, err)
+			}
+		}
+		if end.Before(start) {
+			return fmt.Errorf("line %d: end of range before its start: %s", n, fields[0])
+		}
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			c.Add(day)
+		}
+	}
+	return scanner.Err()
+}
+
+// readICS adds the days covered by the events of an iCalendar file to c.
+func readICS(c *Calendar, r io.Reader) error {
+	var (
+		lines   []string
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		// Long lines are folded onto continuation lines starting with a space.
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	var start, end time.Time
+	var allDay, inEvent bool
+	for n, line := range lines {
+		nameAndValue := strings.SplitN(line, ":", 2)
+		if len(nameAndValue) != 2 {
+			continue
+		}
+		name, value := nameAndValue[0], nameAndValue[1]
+		property := strings.ToUpper(strings.SplitN(name, ";", 2)[0])
+		var err error
+		switch {
+		case property == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end = time.Time{}, time.Time{}
+		case property == "END" && value == "VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return fmt.Errorf("line %d: event without DTSTART", n+1)
+			}
+			addEvent(c, start, end, allDay)
+		case inEvent && property == "DTSTART":
+			start, allDay, err = parseICSTime(name, value)
+		case inEvent && property == "DTEND":
+			end, _, err = parseICSTime(name, value)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n+1, err)
+		}
+	}
+	return nil
+}
+
+// parseICSTime parses the value of a DTSTART or DTEND property, and reports
+// whether it is a whole day.
+func parseICSTime(name, value string) (time.Time, bool, error) {
+	if len(value) == len("20060102") {
+		t, err := time.Parse("20060102", value)
+		return t, true, err
+	}
+	loc := time.Local
+	if strings.HasSuffix(value, "Z") {
+		loc = time.UTC
+		value = strings.TrimSuffix(value, "Z")
+	} else if i := strings.Index(strings.ToUpper(name), "TZID="); i >= 0 {
+		tzid := strings.SplitN(name[i+len("TZID="):], ";", 2)[0]
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// addEvent adds the days an event covers to c. The end of an event is
+// exclusive, so whole-day events end on the day before their end date.
+func addEvent(c *Calendar, start, end time.Time, allDay bool) {
+	switch {
+	case end.IsZero():
+		end = start
+	case allDay:
+		end = end.AddDate(0, 0, -1)
+	default:
+		end = end.Add(-time.Second)
+	}
+	last := end.Format(dateLayout)
+	for day := start; day.Format(dateLayout) <= last; day = day.AddDate(0, 0, 1) {
+		c.Add(day)
+	}
+}
+
+// CalendarPolicy decides what happens to a run that falls on a calendar day.
+type CalendarPolicy int
+
+const (
+	// CalendarSkip drops the run and records it as Skipped.
+	CalendarSkip CalendarPolicy = iota
+	// CalendarShift moves the run to the same time on the next business day,
+	// a Monday to Friday that is in none of the task's calendars.
+	CalendarShift
+)
+
+// Calendarer is an optional interface, see ScheduleOptions, that keeps runs off
+// the days of the named calendars.
+type Calendarer interface {
+	Calendars() []string
+	CalendarPolicy() CalendarPolicy
+}
+
+// calendarsOf returns the calendar names and policy of the task.
+func calendarsOf(task Task) ([]string, CalendarPolicy) {
+	for _, v := range optionSources(task) {
+		if c, ok := v.(Calendarer); ok {
+			return c.Calendars(), c.CalendarPolicy()
+		}
+	}
+	return nil, CalendarSkip
+}
+
+// calendarSchedule keeps the activations of a schedule off the days of a set
+// of calendars. Calendars are looked up by name on every activation, so they
+// may be registered or reloaded at any time.
+type calendarSchedule struct {
+	Schedule
+	calendars []string
+	policy    CalendarPolicy
+}
+
+// withCalendars returns schedule kept off the days of the task's calendars.
+func withCalendars(schedule Schedule, task Task) Schedule {
+	names, policy := calendarsOf(task)
+	if len(names) == 0 {
+		return schedule
+	}
+	return &calendarSchedule{Schedule: schedule, calendars: names, policy: policy}
+}
+
+// Next returns the next activation after t that isn't on a calendar day,
+// shifting it or skipping over it according to the policy.
+func (s *calendarSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(5, 0, 0)
+	for {
+		next := s.Schedule.Next(t)
+		if next.IsZero() || next.After(limit) {
+			return time.Time{}
+		}
+		if !s.blocked(next) {
+			return next
+		}
+		if s.policy == CalendarShift {
+			return s.shift(next, limit)
+		}
+		t = next
+	}
+}
+
+// skipped returns the activations of the underlying schedule after from and
+// before to that are dropped for falling on a calendar day.
+func (s *calendarSchedule) skipped(from, to time.Time) []time.Time {
+	if s.policy != CalendarSkip {
+		return nil
+	}
+	var times []time.Time
+	for t := s.Schedule.Next(from); !t.IsZero() && t.Before(to); t = s.Schedule.Next(t) {
+		if s.blocked(t) {
+			times = append(times, t)
+		}
+	}
+	return times
+}
+
+// local returns t in the time zone of the schedule, which decides what day it
+// falls on.
+func (s *calendarSchedule) local(t time.Time) time.Time {
+	if loc := locationOf(s.Schedule); loc != nil {
+		return t.In(loc)
+	}
+	return t
+}
+
+// blocked returns true if t falls on a day of one of the calendars.
+func (s *calendarSchedule) blocked(t time.Time) bool {
+	t = s.local(t)
+	for _, name := range s.calendars {
+		if c := lookupCalendar(name); c != nil && c.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// shift returns the same time of day as t on the next business day, or the
+// zero time if there is none before limit. Days are counted in the time zone
+// of the schedule, as in blocked.
+func (s *calendarSchedule) shift(t, limit time.Time) time.Time {
+	loc := t.Location()
+	for t = s.local(t); s.blocked(t) || t.Weekday() == time.Saturday || t.Weekday() == time.Sunday; {
+		if t = t.AddDate(0, 0, 1); t.After(limit) {
+			return time.Time{}
+		}
+	}
+	return t.In(loc)
+}
@@ -0,0 +1,206 @@
+package scheduler
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// days returns the days of the calendar, in order.
+func days(c *Calendar) []string {
+	var days []string
+	for day := range c.days {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+func TestReadDateList(t *testing.T) {
+	c := NewCalendar("list")
+	list := `# Holidays
+2021-12-24 Christmas Eve
+
+2021-12-30..2022-01-02  New Year
+  2022-04-15
+`
+	if err := readDateList(c, strings.NewReader(list)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"2021-12-24", "2021-12-30", "2021-12-31", "2022-01-01", "2022-01-02", "2022-04-15"}
+	if got := days(c); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestReadDateListErrors(t *testing.T) {
+	tests := []struct {
+		list string
+		err  string
+	}{
+		{"2021-12-24\n2021-13-01\n", "line 2:"},
+		{"2021-12-31..2021-12-01\n", "line 1: end of range before its start"},
+		{"2021-12-01..2021-12-02..2021-12-03\n", "line 1: too many ranges"},
+		{"24/12/2021\n", "line 1:"},
+	}
+
+	for _, test := range tests {
+		err := readDateList(NewCalendar("list"), strings.NewReader(test.list))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected an error containing %q, got %v", test.list, test.err, err)
+		}
+	}
+}
+
+func TestReadICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		// A whole-day event ends the day before its end date.
+		"BEGIN:VEVENT",
+		"SUMMARY:Christmas",
+		"DTSTART;VALUE=DATE:20211224",
+		"DTEND;VALUE=DATE:20211227",
+		"END:VEVENT",
+		// A timed event covers the days it touches in its own zone.
+		"BEGIN:VEVENT",
+		"DTSTART;TZID=Europe/Berlin:20211231T220000",
+		"DTEND;TZID=Europe/Berlin:20220101T020000",
+		"END:VEVENT",
+		// Ending at midnight doesn't reach into the next day.
+		"BEGIN:VEVENT",
+		"DTSTART:20211001T090000Z",
+		"DTEND:20211002T000000Z",
+		"END:VEVENT",
+		// Without an end, an event covers its start day; lines may be folded.
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:2021",
+		" 1105",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	c := NewCalendar("ics")
+	if err := readICS(c, strings.NewReader(ics)); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"2021-10-01", "2021-11-05", "2021-12-24", "2021-12-25", "2021-12-26", "2021-12-31", "2022-01-01"}
+	if got := days(c); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestReadICSErrors(t *testing.T) {
+	tests := []struct {
+		ics string
+		err string
+	}{
+		{"BEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\n", "line 3: event without DTSTART"},
+		{"BEGIN:VEVENT\nDTSTART;TZID=Nowhere/City:20211001T090000\nEND:VEVENT\n", "line 2:"},
+		{"BEGIN:VEVENT\nDTSTART:2021-10-01\nEND:VEVENT\n", "line 2:"},
+	}
+
+	for _, test := range tests {
+		err := readICS(NewCalendar("ics"), strings.NewReader(test.ics))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected an error containing %q, got %v", test.ics, test.err, err)
+		}
+	}
+}
+
+func TestLoadCalendarFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"holidays.ics": "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20211224\nEND:VEVENT\n",
+		"holidays.txt": "2021-12-24\n",
+	}
+	for file, content := range files {
+		path := filepath.Join(dir, file)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		name := "load-" + file
+		if _, err := LoadCalendarFile(name, path); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		c := lookupCalendar(name)
+		if c == nil || !c.Contains(time.Date(2021, 12, 24, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: expected a registered calendar holding 2021-12-24", file)
+		}
+	}
+
+	if _, err := LoadCalendarFile("load-missing", filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestCalendarPolicy(t *testing.T) {
+	// Monday 2021-10-04, and Friday 2021-10-08 to Monday 2021-10-11.
+	RegisterCalendar(NewCalendar("policy",
+		time.Date(2021, 10, 4, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 11, 0, 0, 0, 0, time.UTC),
+	))
+	daily, err := ParseStandard("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		policy   CalendarPolicy
+		from     time.Time
+		expected time.Time
+	}{
+		{CalendarSkip, time.Date(2021, 10, 3, 10, 0, 0, 0, time.UTC), time.Date(2021, 10, 5, 9, 0, 0, 0, time.UTC)},
+		{CalendarShift, time.Date(2021, 10, 3, 10, 0, 0, 0, time.UTC), time.Date(2021, 10, 5, 9, 0, 0, 0, time.UTC)},
+		{CalendarSkip, time.Date(2021, 10, 7, 10, 0, 0, 0, time.UTC), time.Date(2021, 10, 9, 9, 0, 0, 0, time.UTC)},
+		// Shifting passes over the weekend as well as calendar days.
+		{CalendarShift, time.Date(2021, 10, 7, 10, 0, 0, 0, time.UTC), time.Date(2021, 10, 12, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		schedule := &calendarSchedule{Schedule: daily, calendars: []string{"policy"}, policy: test.policy}
+		if next := schedule.Next(test.from); !next.Equal(test.expected) {
+			t.Errorf("policy %d from %s: expected %s, got %s", test.policy, test.from, test.expected, next)
+		}
+	}
+
+	schedule := &calendarSchedule{Schedule: daily, calendars: []string{"policy"}, policy: CalendarSkip}
+	skipped := schedule.skipped(time.Date(2021, 10, 1, 10, 0, 0, 0, time.UTC), time.Date(2021, 10, 12, 10, 0, 0, 0, time.UTC))
+	expected := []time.Time{
+		time.Date(2021, 10, 4, 9, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 8, 9, 0, 0, 0, time.UTC),
+		time.Date(2021, 10, 11, 9, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(skipped, expected) {
+		t.Errorf("expected %v to be skipped, got %v", expected, skipped)
+	}
+}
+
+func TestCalendarShiftInScheduleZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Friday 2021-11-05 in New York; clocks go back early on Sunday.
+	RegisterCalendar(NewCalendar("shift-zone", time.Date(2021, 11, 5, 0, 0, 0, 0, newYork)))
+	nightly, err := ParseStandard("CRON_TZ=America/New_York 30 0 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schedule := &calendarSchedule{Schedule: nightly, calendars: []string{"shift-zone"}, policy: CalendarShift}
+
+	// The scheduler asks in UTC, but days are those of the schedule.
+	from := time.Date(2021, 11, 5, 0, 0, 0, 0, time.UTC)
+	expected := time.Date(2021, 11, 8, 0, 30, 0, 0, newYork)
+	next := schedule.Next(from)
+	if !next.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, next.In(newYork))
+	}
+	if next.Location() != time.UTC {
+		t.Errorf("expected the time in the zone asked in, got %s", next.Location())
+	}
+}
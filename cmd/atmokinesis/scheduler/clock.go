@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock tells the scheduler what time it is and lets it wait for time to pass.
+// Run timeouts always use the wall clock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is the part of a time.Timer the scheduler relies on.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// RealClock returns a Clock backed by the time package.
+func RealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// FakeClock is a Clock that only moves when it is told to, so schedules can be
+// driven deterministically, e.g. in tests.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed *sync.Cond
+}
+
+// NewFakeClock returns a FakeClock stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	f := &FakeClock{now: now}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *FakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *FakeClock) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, when: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+	return t
+}
+
+func (f *FakeClock) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Advance moves the clock forward by d, firing every timer that comes due on
+// the way in order, see Set.
+func (f *FakeClock) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the clock to now, firing every timer that comes due on the way in
+// order. The clock never moves backwards. Timers fire with the new time, as
+// they would after the process was suspended, so a large jump makes them late.
+func (f *FakeClock) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.Before(f.now) {
+		return
+	}
+	f.now = now
+
+	sort.SliceStable(f.timers, func(i, j int) bool {
+		return f.timers[i].when.Before(f.timers[j].when)
+	})
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.when.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- now
+	}
+	for i := len(pending); i < len(f.timers); i++ {
+		f.timers[i] = nil
+	}
+	f.timers = pending
+	f.changed.Broadcast()
+}
+
+// BlockUntil waits until at least n timers are waiting on the clock. It lets a
+// caller know the scheduler has gone back to sleep before moving the clock.
+func (f *FakeClock) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	c     chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	f := t.clock
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, pending := range f.timers {
+		if pending == t {
+			f.timers = append(f.timers[:i], f.timers[i+1:]...)
+			f.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
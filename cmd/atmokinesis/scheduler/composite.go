@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// UnionSchedule activates whenever any of its schedules does.
+type UnionSchedule []Schedule
+
+// Next returns the earliest next activation of the schedules, or the zero time
+// if none of them activates again.
+func (u UnionSchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, s := range u {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// ExcludeSchedule activates whenever Include does, except at the times that
+// Exclude activates.
+type ExcludeSchedule struct {
+	Include, Exclude Schedule
+}
+
+// maxExcluded bounds how many activations in a row ExcludeSchedule passes over
+// before it gives up, so that excluding every activation of a frequent
+// schedule, such as every minute, doesn't take years of activations to notice.
+const maxExcluded = 100000
+
+// Next returns the next activation of Include that isn't one of Exclude, or the
+// zero time if there is none within five years or within the next maxExcluded
+// activations of Include.
+func (s ExcludeSchedule) Next(t time.Time) time.Time {
+	limit := t.AddDate(5, 0, 0)
+	for i := 0; i < maxExcluded; i++ {
+		next := s.Include.Next(t)
+		if next.IsZero() || next.After(limit) {
+			return time.Time{}
+		}
+		if !activatesAt(s.Exclude, next) {
+			return next
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// activatesAt returns true if t is an activation time of the schedule.
+func activatesAt(s Schedule, t time.Time) bool {
+	return s.Next(t.Add(-time.Nanosecond)).Equal(t)
+}
+
+// MultiScheduler is implemented by a Task that runs on several cron strings.
+// The task runs whenever its Schedule or any of its Schedules activates, and
+// all of the runs are recorded under its TaskID.
+type MultiScheduler interface {
+	Schedules() []Cron
+}
+
+// Excluder is implemented by a Task that must not run at the activation times
+// of some cron strings, even when its schedules activate.
+type Excluder interface {
+	Exclusions() []Cron
+}
+
+// cronsOf returns the cron strings the task runs on and those it is excluded
+// from.
+func cronsOf(task Task) (schedules, exclusions []Cron) {
+	schedules = []Cron{task.Schedule()}
+	if m, ok := task.(MultiScheduler); ok {
+		schedules = append(schedules, m.Schedules()...)
+	}
+	if e, ok := task.(Excluder); ok {
+		exclusions = e.Exclusions()
+	}
+	return schedules, exclusions
+}
+
+// scheduleFor parses the cron strings of the task into a single schedule, as
+// a union of its schedules minus the union of its exclusions.
+func scheduleFor(task Task) (Schedule, error) {
+	crons, exclusions := cronsOf(task)
+	schedule, err := parseUnion(crons, task.TaskID())
+	if err != nil || len(exclusions) == 0 {
+		return schedule, err
+	}
+	exclude, err := parseUnion(exclusions, task.TaskID())
+	if err != nil {
+		return nil, err
+	}
+	return ExcludeSchedule{Include: schedule, Exclude: exclude}, nil
+}
+
+// taskSchedule returns the schedule the task runs on once InitScheduler has
+// added it: that of its cron strings, with its options applied.
+func taskSchedule(task Task) (Schedule, error) {
+	schedule, err := scheduleFor(task)
+	if err != nil {
+		return nil, err
+	}
+	return withTaskOptions(schedule, task), nil
+}
+
+// parseUnion parses the cron strings like ParseStandard, resolving H from the
+// task ID, and returns the union of their schedules. A single cron string
+// gives its schedule as it is.
+func parseUnion(crons []Cron, id ID) (Schedule, error) {
+	var union UnionSchedule
+	for _, cron := range crons {
+		if cron == "" {
+			continue
+		}
+		s, err := standardParser.ForTask(id).Parse(cron)
+		if err != nil {
+			return nil, err
+		}
+		union = append(union, s)
+	}
+	switch len(union) {
+	case 0:
+		return nil, fmt.Errorf("No CRON string provided")
+	case 1:
+		return union[0], nil
+	}
+	return union, nil
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnionSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		crons    []Cron
+		from     string
+		expected []string // "" for no further activation
+	}{
+		{"overlapping", []Cron{"0 9 * * *", "0 9 * * MON", "0 */3 * * *"}, "2021-10-04T07:00:00Z", []string{
+			"2021-10-04T09:00:00Z", "2021-10-04T12:00:00Z", "2021-10-04T15:00:00Z"}},
+		{"adjacent", []Cron{"0-29/15 9 * * *", "30-59/15 9 * * *"}, "2021-10-04T09:00:00Z", []string{
+			"2021-10-04T09:15:00Z", "2021-10-04T09:30:00Z", "2021-10-04T09:45:00Z", "2021-10-05T09:00:00Z"}},
+		{"adjacent days", []Cron{"0 23 * * *", "0 0 * * *"}, "2021-10-04T22:00:00Z", []string{
+			"2021-10-04T23:00:00Z", "2021-10-05T00:00:00Z", "2021-10-05T23:00:00Z"}},
+		{"one ends", []Cron{"0 0 1 1 * 2022", "0 0 1 */6 *"}, "2021-10-01T00:00:00Z", []string{
+			"2022-01-01T00:00:00Z", "2022-07-01T00:00:00Z", "2023-01-01T00:00:00Z"}},
+		{"all end", []Cron{"0 0 1 1 * 2022", "0 0 1 7 * 2022"}, "2021-10-01T00:00:00Z", []string{
+			"2022-01-01T00:00:00Z", "2022-07-01T00:00:00Z", ""}},
+	}
+
+	for _, test := range tests {
+		var union UnionSchedule
+		for _, cron := range test.crons {
+			s, err := ParseStandard(cron)
+			if err != nil {
+				t.Fatalf("%s: %v", test.name, err)
+			}
+			union = append(union, s)
+		}
+		next, _ := time.Parse(time.RFC3339, test.from)
+		for _, value := range test.expected {
+			var expected time.Time
+			if value != "" {
+				expected, _ = time.Parse(time.RFC3339, value)
+			}
+			next = union.Next(next)
+			if !next.Equal(expected) {
+				t.Errorf("%s: expected %s, got %s", test.name, expected, next)
+				break
+			}
+		}
+	}
+}
+
+func TestExcludeSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  Cron
+		exclude  []Cron
+		from     string
+		expected []string // "" for no further activation
+	}{
+		{"some", "0 * * * *", []Cron{"0 12-13 * * *"}, "2021-10-04T10:30:00Z", []string{
+			"2021-10-04T11:00:00Z", "2021-10-04T14:00:00Z"}},
+		{"weekends", "0 9 * * *", []Cron{"* * * * SAT,SUN"}, "2021-10-08T10:00:00Z", []string{
+			"2021-10-11T09:00:00Z"}},
+		{"not lined up", "30 * * * *", []Cron{"0 * * * *"}, "2021-10-04T10:00:00Z", []string{
+			"2021-10-04T10:30:00Z"}},
+		{"several", "0 9 * * *", []Cron{"0 9 * * MON", "0 9 * * TUE"}, "2021-10-03T10:00:00Z", []string{
+			"2021-10-06T09:00:00Z"}},
+
+		// Excluding every activation ends the schedule, and promptly.
+		{"every hour", "0 * * * *", []Cron{"0 * * * *"}, "2021-10-04T10:00:00Z", []string{""}},
+		{"every minute", "* * * * *", []Cron{"* * * * *"}, "2021-10-04T10:00:00Z", []string{""}},
+		{"every minute by parts", "* * * * *", []Cron{"0-29 * * * *", "30-59 * * * *"}, "2021-10-04T10:00:00Z", []string{""}},
+	}
+
+	for _, test := range tests {
+		include, err := ParseStandard(test.include)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		exclude, err := parseUnion(test.exclude, "")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		schedule := ExcludeSchedule{Include: include, Exclude: exclude}
+		next, _ := time.Parse(time.RFC3339, test.from)
+		for _, value := range test.expected {
+			var expected time.Time
+			if value != "" {
+				expected, _ = time.Parse(time.RFC3339, value)
+			}
+			next = schedule.Next(next)
+			if !next.Equal(expected) {
+				t.Errorf("%s: expected %s, got %s", test.name, expected, next)
+				break
+			}
+		}
+	}
+}
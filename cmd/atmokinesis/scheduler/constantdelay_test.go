@@ -0,0 +1,126 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEvery(t *testing.T) {
+	tests := []struct {
+		spec  Cron
+		delay time.Duration
+		err   bool
+	}{
+		{"@every 1h30m", 90 * time.Minute, false},
+		{"@every 5m", 5 * time.Minute, false},
+		{"@every 45s", 45 * time.Second, false},
+		{"@every 1s", time.Second, false},
+		{"@every 1m30s", 90 * time.Second, false},
+		{"@every  10s ", 10 * time.Second, false},
+		{"CRON_TZ=Europe/Berlin @every 2h", 2 * time.Hour, false},
+
+		// Below a second is rounded up, and fractions of a second are dropped.
+		{"@every 500ms", time.Second, false},
+		{"@every 1ns", time.Second, false},
+		{"@every 1.5s", time.Second, false},
+		{"@every 2999ms", 2 * time.Second, false},
+
+		{"@every 0s", 0, true},
+		{"@every -1m", 0, true},
+		{"@every 1 hour", 0, true},
+		{"@every", 0, true},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %v", test.spec, schedule)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.spec, err)
+			continue
+		}
+		every, ok := schedule.(ConstantDelaySchedule)
+		if !ok {
+			t.Errorf("%q: expected a ConstantDelaySchedule, got %T", test.spec, schedule)
+			continue
+		}
+		if every.Delay != test.delay {
+			t.Errorf("%q: expected a delay of %s, got %s", test.spec, test.delay, every.Delay)
+		}
+	}
+}
+
+func TestConstantDelayNext(t *testing.T) {
+	tests := []struct {
+		time     string
+		delay    time.Duration
+		expected string
+	}{
+		// Simple cases
+		{"Mon Jul 9 14:45 2012", 15*time.Minute + 50*time.Nanosecond, "Mon Jul 9 15:00 2012"},
+		{"Mon Jul 9 14:59 2012", 15 * time.Minute, "Mon Jul 9 15:14 2012"},
+		{"Mon Jul 9 14:59:59 2012", 15 * time.Minute, "Mon Jul 9 15:14:59 2012"},
+
+		// Wrap around hours
+		{"Mon Jul 9 15:45 2012", 35 * time.Minute, "Mon Jul 9 16:20 2012"},
+
+		// Wrap around days
+		{"Mon Jul 9 23:46 2012", 14 * time.Minute, "Tue Jul 10 00:00 2012"},
+		{"Mon Jul 9 23:45 2012", 35 * time.Minute, "Tue Jul 10 00:20 2012"},
+		{"Mon Jul 9 23:35:51 2012", 44*time.Minute + 24*time.Second, "Tue Jul 10 00:20:15 2012"},
+		{"Mon Jul 9 23:35:51 2012", 25*time.Hour + 44*time.Minute + 24*time.Second, "Thu Jul 11 01:20:15 2012"},
+
+		// Wrap around months
+		{"Mon Jul 9 23:35 2012", 91*24*time.Hour + 25*time.Minute, "Thu Oct 9 00:00 2012"},
+
+		// Wrap around minute, hour, day, month, and year
+		{"Mon Dec 31 23:59:45 2012", 15 * time.Second, "Tue Jan 1 00:00:00 2013"},
+
+		// Sub-minute intervals
+		{"Mon Jul 9 14:45:00 2012", 30 * time.Second, "Mon Jul 9 14:45:30 2012"},
+		{"Mon Jul 9 14:45:50 2012", 15 * time.Second, "Mon Jul 9 14:46:05 2012"},
+		{"Mon Jul 9 14:45:59 2012", time.Second, "Mon Jul 9 14:46:00 2012"},
+
+		// Round to nearest second on the delay
+		{"Mon Jul 9 14:45 2012", 15*time.Minute + 50*time.Nanosecond, "Mon Jul 9 15:00 2012"},
+
+		// Round up to 1 second if the duration is less.
+		{"Mon Jul 9 14:45:00 2012", 15 * time.Millisecond, "Mon Jul 9 14:45:01 2012"},
+
+		// Round to nearest second when calculating the next time.
+		{"Mon Jul 9 14:45:00.005 2012", 15 * time.Minute, "Mon Jul 9 15:00 2012"},
+		{"Mon Jul 9 14:45:00.999 2012", time.Second, "Mon Jul 9 14:45:01 2012"},
+
+		// Round to nearest second for both.
+		{"Mon Jul 9 14:45:00.005 2012", 15*time.Minute + 50*time.Nanosecond, "Mon Jul 9 15:00 2012"},
+	}
+
+	for _, c := range tests {
+		actual := Every(c.delay).Next(getTime(c.time))
+		expected := getTime(c.expected)
+		if actual != expected {
+			t.Errorf("%s, \"%s\": (expected) %v != %v (actual)", c.time, c.delay, expected, actual)
+		}
+	}
+}
+
+func getTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	layouts := []string{
+		"Mon Jan 2 15:04 2006",
+		"Mon Jan 2 15:04:05 2006",
+		"Mon Jan 2 15:04:05.000 2006",
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	panic("could not parse time value " + value)
+}
@@ -3,6 +3,7 @@ package scheduler
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"strconv"
 	"strings"
@@ -20,6 +21,8 @@ const (
 	Dow
 	DowOptional
 	Descriptor
+	Year
+	YearOptional
 )
 
 var places = []ParseOption{
@@ -29,6 +32,7 @@ var places = []ParseOption{
 	Dom,
 	Month,
 	Dow,
+	Year,
 }
 
 var defaults = []string{
@@ -38,11 +42,13 @@ var defaults = []string{
 	"*",
 	"*",
 	"*",
+	"*",
 }
 
 type Parser struct {
 	options   ParseOption
 	optionals int
+	seed      ID
 }
 
 func NewParser(options ParseOption) Parser {
@@ -51,15 +57,47 @@ func NewParser(options ParseOption) Parser {
 		options |= Dow
 		optionals++
 	}
-	return Parser{options, optionals}
+	if options&YearOptional > 0 {
+		options |= Year
+		optionals++
+	}
+	return Parser{options: options, optionals: optionals}
+}
+
+// ForTask returns a parser that resolves the H in cron strings from the given
+// task ID, so tasks sharing a cron string run at different, but fixed, times.
+// Without it, H is resolved from an empty ID.
+func (p Parser) ForTask(id ID) Parser {
+	p.seed = id
+	return p
 }
 
 func (p Parser) Parse(cron Cron) (Schedule, error) {
 	if len(cron) == 0 {
 		return nil, fmt.Errorf("No CRON string provided")
 	}
-	if cron[0] == '@' && p.options&Descriptor > 0 {
-		return parseDescriptor(cron.ToString())
+
+	// Extract the time zone, if present
+	var (
+		loc *time.Location
+		err error
+	)
+	spec := cron.ToString()
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		i := strings.Index(spec, " ")
+		if i < 0 {
+			return nil, fmt.Errorf("Missing schedule after time zone: %s", cron)
+		}
+		eq := strings.Index(spec, "=")
+		if loc, err = time.LoadLocation(spec[eq+1 : i]); err != nil {
+			return nil, fmt.Errorf("Provided bad location %s: %s", spec[eq+1:i], err)
+		}
+		spec = strings.TrimSpace(spec[i:])
+		cron = Cron(spec)
+	}
+
+	if len(spec) > 0 && spec[0] == '@' && p.options&Descriptor > 0 {
+		return parseDescriptor(spec, loc)
 	}
 
 	// Figure out how many fields we need
@@ -85,7 +123,13 @@ func (p Parser) Parse(cron Cron) (Schedule, error) {
 	// Fill in missing fields
 	fields = expandFields(fields, p.options)
 
-	var err error
+	// Resolve H into values spread by the task ID
+	for i, r := range hashBounds {
+		if fields[i], err = expandHash(fields[i], r, hashOf(p.seed, i)); err != nil {
+			return nil, err
+		}
+	}
+
 	field := func(field string, r bounds) uint64 {
 		if err != nil {
 			return 0
@@ -95,26 +139,38 @@ func (p Parser) Parse(cron Cron) (Schedule, error) {
 		return bits
 	}
 
+	schedule := &SpecSchedule{Location: loc}
+	dayField := func(field string, get func(string, *SpecSchedule) (uint64, error)) uint64 {
+		if err != nil {
+			return 0
+		}
+		var bits uint64
+		bits, err = get(field, schedule)
+		return bits
+	}
+
 	var (
 		second     = field(fields[0], seconds)
 		minute     = field(fields[1], minutes)
 		hour       = field(fields[2], hours)
-		dayofmonth = field(fields[3], dom)
+		dayofmonth = dayField(fields[3], getDomField)
 		month      = field(fields[4], months)
-		dayofweek  = field(fields[5], dow)
+		dayofweek  = dayField(fields[5], getDowField)
 	)
 	if err != nil {
 		return nil, err
 	}
+	if schedule.Years, err = getYears(fields[6]); err != nil {
+		return nil, err
+	}
 
-	return &SpecSchedule{
-		Second: second,
-		Minute: minute,
-		Hour:   hour,
-		Dom:    dayofmonth,
-		Month:  month,
-		Dow:    dayofweek,
-	}, nil
+	schedule.Second = second
+	schedule.Minute = minute
+	schedule.Hour = hour
+	schedule.Dom = dayofmonth
+	schedule.Month = month
+	schedule.Dow = dayofweek
+	return schedule, nil
 }
 
 func expandFields(fields []string, options ParseOption) []string {
@@ -135,7 +191,7 @@ func expandFields(fields []string, options ParseOption) []string {
 }
 
 var standardParser = NewParser(
-	Minute | Hour | Dom | Month | Dow | Descriptor,
+	Minute | Hour | Dom | Month | Dow | YearOptional | Descriptor,
 )
 
 func ParseStandard(stdCron Cron) (Schedule, error) {
@@ -143,15 +199,20 @@ func ParseStandard(stdCron Cron) (Schedule, error) {
 }
 
 var defaultParser = NewParser(
-	Second | Minute | Hour | Dom | Month | DowOptional | Descriptor,
+	Second | Minute | Hour | Dom | Month | DowOptional | YearOptional | Descriptor,
 )
 
 // Parse returns a new crontab schedule representing the given spec.
 // It returns a descriptive error if the spec is not valid.
 //
 // It accepts
-//   - Full crontab specs, e.g. "* * * * * ?"
+//   - Full crontab specs, e.g. "* * * * * ?", with an optional year, e.g. "0 0 0 L * ? 2030"
+//   - "L", "LW" and "nW" in the day of month, e.g. "0 0 0 15W * ?"
+//   - "d#n" and "dL" in the day of week, e.g. "0 0 9 ? * TUE#2"
 //   - Descriptors, e.g. "@midnight", "@every 1h30m"
+//   - Either of the above behind a time zone, e.g. "CRON_TZ=Europe/Berlin 0 0 9 * * *"
+//   - "H", "H(a-b)", "H/n" and "H(a-b)/n" for a value picked by hashing the
+//     task ID, e.g. "0 H H(0-5) * * ?", see Parser.ForTask
 func Parse(spec Cron) (Schedule, error) {
 	return defaultParser.Parse(spec)
 }
@@ -172,19 +233,187 @@ func getField(field string, r bounds) (uint64, error) {
 	return bits, nil
 }
 
+// hashBounds are the bounds H is resolved in for each field, in the order of
+// places. The day of month stops at 28 so that H matches in every month.
+var hashBounds = []bounds{seconds, minutes, hours, {1, 28, nil}, months, dow}
+
+// hashOf returns a hash of the task ID for the given field.
+func hashOf(id ID, field int) uint {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s/%d", id, field)
+	return uint(h.Sum32())
+}
+
+// expandHash replaces the H terms of a field, written as H, H(a-b), H/n or
+// H(a-b)/n, with the values they resolve to for the given hash.
+func expandHash(field string, r bounds, hash uint) (string, error) {
+	terms := strings.Split(field, ",")
+	for i, term := range terms {
+		if !strings.HasPrefix(strings.ToUpper(term), "H") {
+			continue
+		}
+		var (
+			rangeAndStep = strings.Split(term[1:], "/")
+			start, end   = r.min, r.max
+			err          error
+		)
+		if within := rangeAndStep[0]; within != "" {
+			lowAndHigh := strings.Split(strings.TrimSuffix(strings.TrimPrefix(within, "("), ")"), "-")
+			if !strings.HasPrefix(within, "(") || !strings.HasSuffix(within, ")") || len(lowAndHigh) != 2 {
+				return "", fmt.Errorf("H takes a range as H(a-b): %s", term)
+			}
+			if start, err = mustParseInt(lowAndHigh[0]); err != nil {
+				return "", err
+			}
+			if end, err = mustParseInt(lowAndHigh[1]); err != nil {
+				return "", err
+			}
+			if start < r.min || end > r.max || start > end {
+				return "", fmt.Errorf("Range of H must lie within %d-%d: %s", r.min, r.max, term)
+			}
+		}
+		switch len(rangeAndStep) {
+		case 1:
+			terms[i] = strconv.Itoa(int(start + hash%(end-start+1)))
+		case 2:
+			step, err := mustParseInt(rangeAndStep[1])
+			if err != nil {
+				return "", err
+			}
+			if step == 0 {
+				return "", fmt.Errorf("Step of range should be a positive number: %s", term)
+			}
+			offset := hash % step
+			if start+offset > end {
+				offset = 0
+			}
+			terms[i] = fmt.Sprintf("%d-%d/%d", start+offset, end, step)
+		default:
+			return "", fmt.Errorf("Too many slashes: %s", term)
+		}
+	}
+	return strings.Join(terms, ","), nil
+}
+
+// getDomField returns the bits of the plain ranges of a day-of-month field. The
+// "L", "LW" and "nW" terms are set on s instead.
+func getDomField(field string, s *SpecSchedule) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		switch upper := strings.ToUpper(expr); {
+		case upper == "L":
+			s.LastDom = true
+		case upper == "LW":
+			s.LastWeekdayDom = true
+		case strings.HasSuffix(upper, "W"):
+			day, err := mustParseInt(expr[:len(expr)-1])
+			if err != nil {
+				return 0, fmt.Errorf("Nearest weekday needs a single day, e.g. 15W: %s", expr)
+			}
+			if day < dom.min || day > dom.max {
+				return 0, fmt.Errorf("Day of nearest weekday (%d) outside %d-%d: %s", day, dom.min, dom.max, expr)
+			}
+			s.NearestWeekdayDom |= 1 << day
+		case strings.Contains(upper, "L") || strings.Contains(upper, "#"):
+			return 0, fmt.Errorf("Only L, LW and nW are allowed in the day of month: %s", expr)
+		default:
+			bit, err := getRange(expr, dom)
+			if err != nil {
+				return 0, err
+			}
+			bits |= bit
+		}
+	}
+	return bits, nil
+}
+
+// getDowField returns the bits of the plain ranges of a day-of-week field. The
+// "d#n" and "dL" terms are set on s instead.
+func getDowField(field string, s *SpecSchedule) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		switch {
+		case strings.Contains(expr, "#"):
+			dayAndNth := strings.Split(expr, "#")
+			if len(dayAndNth) != 2 {
+				return 0, fmt.Errorf("Too many hashes: %s", expr)
+			}
+			day, err := parseIntOrName(dayAndNth[0], dow.names)
+			if err != nil {
+				return 0, fmt.Errorf("Nth day of week needs a single day, e.g. 2#2: %s", expr)
+			}
+			if day > dow.max {
+				return 0, fmt.Errorf("Day of week (%d) above maximum (%d): %s", day, dow.max, expr)
+			}
+			nth, err := mustParseInt(dayAndNth[1])
+			if err != nil {
+				return 0, err
+			}
+			if nth < 1 || nth > 5 {
+				return 0, fmt.Errorf("Occurrence (%d) must be between 1 and 5: %s", nth, expr)
+			}
+			s.NthDow |= 1 << (7*(nth-1) + day)
+		case len(expr) > 1 && strings.HasSuffix(strings.ToUpper(expr), "L"):
+			day, err := parseIntOrName(expr[:len(expr)-1], dow.names)
+			if err != nil {
+				return 0, fmt.Errorf("Last day of week needs a single day, e.g. 5L: %s", expr)
+			}
+			if day > dow.max {
+				return 0, fmt.Errorf("Day of week (%d) above maximum (%d): %s", day, dow.max, expr)
+			}
+			s.LastDow |= 1 << day
+		case strings.EqualFold(expr, "L") || strings.HasSuffix(strings.ToUpper(expr), "W"):
+			return 0, fmt.Errorf("Only d#n and dL are allowed in the day of week: %s", expr)
+		default:
+			bit, err := getRange(expr, dow)
+			if err != nil {
+				return 0, err
+			}
+			bits |= bit
+		}
+	}
+	return bits, nil
+}
+
+// getYears returns the years listed in a year field, or nil if the field
+// matches every year. It takes the same ranges as the other fields.
+func getYears(field string) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, expr := range strings.FieldsFunc(field, func(r rune) bool { return r == ',' }) {
+		start, end, step, extra, err := parseRange(expr, years)
+		if err != nil {
+			return nil, err
+		}
+		if extra == starBit && step == 1 {
+			return nil, nil
+		}
+		for year := start; year <= end; year += step {
+			set[int(year)] = true
+		}
+	}
+	return set, nil
+}
+
 // getRange returns the bits indicated by the given expression:
 //   number | number "-" number [ "/" number ]
 // or error parsing range.
 func getRange(expr string, r bounds) (uint64, error) {
+	start, end, step, extra, err := parseRange(expr, r)
+	if err != nil {
+		return 0, err
+	}
+	return getBits(start, end, step) | extra, nil
+}
+
+// parseRange returns the start, end and step of the given range expression,
+// and the star bit if it is "*" or "?".
+func parseRange(expr string, r bounds) (start, end, step uint, extra uint64, err error) {
 	var (
-		start, end, step uint
-		rangeAndStep     = strings.Split(expr, "/")
-		lowAndHigh       = strings.Split(rangeAndStep[0], "-")
-		singleDigit      = len(lowAndHigh) == 1
-		err              error
+		rangeAndStep = strings.Split(expr, "/")
+		lowAndHigh   = strings.Split(rangeAndStep[0], "-")
+		singleDigit  = len(lowAndHigh) == 1
 	)
 
-	var extra uint64
 	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
 		start = r.min
 		end = r.max
@@ -192,7 +421,7 @@ func getRange(expr string, r bounds) (uint64, error) {
 	} else {
 		start, err = parseIntOrName(lowAndHigh[0], r.names)
 		if err != nil {
-			return 0, err
+			return 0, 0, 0, 0, err
 		}
 		switch len(lowAndHigh) {
 		case 1:
@@ -200,10 +429,10 @@ func getRange(expr string, r bounds) (uint64, error) {
 		case 2:
 			end, err = parseIntOrName(lowAndHigh[1], r.names)
 			if err != nil {
-				return 0, err
+				return 0, 0, 0, 0, err
 			}
 		default:
-			return 0, fmt.Errorf("Too many hyphens: %s", expr)
+			return 0, 0, 0, 0, fmt.Errorf("Too many hyphens: %s", expr)
 		}
 	}
 
@@ -213,7 +442,7 @@ func getRange(expr string, r bounds) (uint64, error) {
 	case 2:
 		step, err = mustParseInt(rangeAndStep[1])
 		if err != nil {
-			return 0, err
+			return 0, 0, 0, 0, err
 		}
 
 		// Special handling: "N/step" means "N-max/step".
@@ -221,23 +450,23 @@ func getRange(expr string, r bounds) (uint64, error) {
 			end = r.max
 		}
 	default:
-		return 0, fmt.Errorf("Too many slashes: %s", expr)
+		return 0, 0, 0, 0, fmt.Errorf("Too many slashes: %s", expr)
 	}
 
 	if start < r.min {
-		return 0, fmt.Errorf("Beginning of range (%d) below minimum (%d): %s", start, r.min, expr)
+		return 0, 0, 0, 0, fmt.Errorf("Beginning of range (%d) below minimum (%d): %s", start, r.min, expr)
 	}
 	if end > r.max {
-		return 0, fmt.Errorf("End of range (%d) above maximum (%d): %s", end, r.max, expr)
+		return 0, 0, 0, 0, fmt.Errorf("End of range (%d) above maximum (%d): %s", end, r.max, expr)
 	}
 	if start > end {
-		return 0, fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", start, end, expr)
+		return 0, 0, 0, 0, fmt.Errorf("Beginning of range (%d) beyond end of range (%d): %s", start, end, expr)
 	}
 	if step == 0 {
-		return 0, fmt.Errorf("Step of range should be a positive number: %s", expr)
+		return 0, 0, 0, 0, fmt.Errorf("Step of range should be a positive number: %s", expr)
 	}
 
-	return getBits(start, end, step) | extra, nil
+	return start, end, step, extra, nil
 }
 
 // parseIntOrName returns the (possibly-named) integer contained in expr.
@@ -285,56 +514,74 @@ func all(r bounds) uint64 {
 }
 
 // parseDescriptor returns a predefined schedule for the expression, or error if none matches.
-func parseDescriptor(descriptor string) (Schedule, error) {
+// The schedule is worked out in loc, if given; "@every" intervals ignore it.
+func parseDescriptor(descriptor string, loc *time.Location) (Schedule, error) {
+	const every = "@every "
+	if strings.HasPrefix(descriptor, every) {
+		duration, err := time.ParseDuration(strings.TrimSpace(descriptor[len(every):]))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse duration %s: %s", descriptor, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("Duration must be positive: %s", descriptor)
+		}
+		return Every(duration), nil
+	}
+
 	switch descriptor {
 	case "@yearly", "@annually":
 		return &SpecSchedule{
-			Second: 1 << seconds.min,
-			Minute: 1 << minutes.min,
-			Hour:   1 << hours.min,
-			Dom:    1 << dom.min,
-			Month:  1 << months.min,
-			Dow:    all(dow),
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      1 << dom.min,
+			Month:    1 << months.min,
+			Dow:      all(dow),
+			Location: loc,
 		}, nil
 
 	case "@monthly":
 		return &SpecSchedule{
-			Second: 1 << seconds.min,
-			Minute: 1 << minutes.min,
-			Hour:   1 << hours.min,
-			Dom:    1 << dom.min,
-			Month:  all(months),
-			Dow:    all(dow),
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      1 << dom.min,
+			Month:    all(months),
+			Dow:      all(dow),
+			Location: loc,
 		}, nil
 
 	case "@weekly":
 		return &SpecSchedule{
-			Second: 1 << seconds.min,
-			Minute: 1 << minutes.min,
-			Hour:   1 << hours.min,
-			Dom:    all(dom),
-			Month:  all(months),
-			Dow:    1 << dow.min,
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      all(dom),
+			Month:    all(months),
+			Dow:      1 << dow.min,
+			Location: loc,
 		}, nil
 
 	case "@daily", "@midnight":
 		return &SpecSchedule{
-			Second: 1 << seconds.min,
-			Minute: 1 << minutes.min,
-			Hour:   1 << hours.min,
-			Dom:    all(dom),
-			Month:  all(months),
-			Dow:    all(dow),
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     1 << hours.min,
+			Dom:      all(dom),
+			Month:    all(months),
+			Dow:      all(dow),
+			Location: loc,
 		}, nil
 
 	case "@hourly":
 		return &SpecSchedule{
-			Second: 1 << seconds.min,
-			Minute: 1 << minutes.min,
-			Hour:   all(hours),
-			Dom:    all(dom),
-			Month:  all(months),
-			Dow:    all(dow),
+			Second:   1 << seconds.min,
+			Minute:   1 << minutes.min,
+			Hour:     all(hours),
+			Dom:      all(dom),
+			Month:    all(months),
+			Dow:      all(dow),
+			Location: loc,
 		}, nil
 	}
 
@@ -0,0 +1,290 @@
+package scheduler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimeZone(t *testing.T) {
+	tests := []struct {
+		spec     Cron
+		policy   DSTPolicy
+		from     string
+		expected string
+	}{
+		// Activations are worked out in the zone, whatever the zone of from.
+		{"CRON_TZ=Europe/Berlin 0 0 9 * * *", DSTRunOnce,
+			"2021-10-01T00:00:00Z", "2021-10-01T09:00:00+02:00"},
+		{"CRON_TZ=Europe/Berlin 0 0 9 * * *", DSTRunOnce,
+			"2021-10-01T08:00:00Z", "2021-10-02T09:00:00+02:00"},
+		{"TZ=America/New_York 0 0 9 * * ?", DSTRunOnce,
+			"2021-10-01T12:00:00Z", "2021-10-01T09:00:00-04:00"},
+		{"CRON_TZ=Asia/Tokyo 0 30 0 1 * ?", DSTRunOnce,
+			"2021-09-30T12:00:00Z", "2021-10-01T00:30:00+09:00"},
+		{"CRON_TZ=UTC @daily", DSTRunOnce,
+			"2021-10-01T12:00:00+02:00", "2021-10-02T00:00:00Z"},
+
+		// Combined with a DST policy, the gap is that of the zone.
+		{"CRON_TZ=America/New_York 0 30 2 * * *", DSTRunOnce,
+			"2021-03-14T00:00:00Z", "2021-03-14T03:00:00-04:00"},
+		{"CRON_TZ=America/New_York 0 30 2 * * *", DSTSkip,
+			"2021-03-14T00:00:00Z", "2021-03-15T02:30:00-04:00"},
+		{"CRON_TZ=Europe/Berlin 0 30 2 * * *", DSTSkip,
+			"2021-03-27T12:00:00Z", "2021-03-29T02:30:00+02:00"},
+		{"CRON_TZ=Europe/Berlin 0 30 2 * * *", DSTRunOnce,
+			"2021-10-30T12:00:00Z", "2021-10-31T02:30:00+02:00"},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.spec, err)
+			continue
+		}
+		schedule = withDSTPolicy(schedule, test.policy)
+		from, _ := time.Parse(time.RFC3339, test.from)
+		expected, _ := time.Parse(time.RFC3339, test.expected)
+		if next := schedule.Next(from); !next.Equal(expected) {
+			t.Errorf("%q from %s: expected %s, got %s", test.spec, from, expected, next)
+		}
+	}
+}
+
+func TestParseTimeZoneErrors(t *testing.T) {
+	tests := []struct {
+		spec Cron
+		err  string
+	}{
+		{"CRON_TZ=Mars/Olympus_Mons 0 0 9 * * *", "Provided bad location Mars/Olympus_Mons"},
+		{"TZ=Nowhere 0 0 9 * * *", "Provided bad location Nowhere"},
+		{"CRON_TZ=Europe/Berlin", "Missing schedule after time zone"},
+		{"CRON_TZ=Europe/Berlin 0 0 25 * * *", "above maximum"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.spec)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.spec)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected an error containing %q, got %q", test.spec, test.err, err)
+		}
+	}
+}
+
+func TestParseSpecialCharacters(t *testing.T) {
+	tests := []struct {
+		spec     Cron
+		from     string
+		expected []string // "" for no further activation
+	}{
+		// Last day of the month, across month ends and in a leap year.
+		{"0 0 0 L * ?", "2021-01-15T00:00:00Z", []string{
+			"2021-01-31T00:00:00Z", "2021-02-28T00:00:00Z", "2021-03-31T00:00:00Z", "2021-04-30T00:00:00Z"}},
+		{"0 0 0 L * ?", "2024-02-01T00:00:00Z", []string{
+			"2024-02-29T00:00:00Z", "2024-03-31T00:00:00Z"}},
+		{"0 0 0 L 2 ?", "2023-03-01T00:00:00Z", []string{
+			"2024-02-29T00:00:00Z", "2025-02-28T00:00:00Z"}},
+
+		// Last weekday of the month, when the month ends on a weekend.
+		{"0 0 0 LW * ?", "2021-01-15T00:00:00Z", []string{
+			"2021-01-29T00:00:00Z", "2021-02-26T00:00:00Z", "2021-03-31T00:00:00Z"}},
+		{"0 0 0 LW * ?", "2020-02-01T00:00:00Z", []string{
+			"2020-02-28T00:00:00Z"}},
+
+		// Nearest weekday, moving off Saturdays and Sundays.
+		{"0 0 0 15W * ?", "2021-05-01T00:00:00Z", []string{
+			"2021-05-14T00:00:00Z", "2021-06-15T00:00:00Z", "2021-07-15T00:00:00Z", "2021-08-16T00:00:00Z"}},
+		// Without leaving the month when it starts on a weekend.
+		{"0 0 0 1W * ?", "2021-04-30T00:00:00Z", []string{
+			"2021-05-03T00:00:00Z", "2021-06-01T00:00:00Z", "2021-07-01T00:00:00Z", "2021-08-02T00:00:00Z"}},
+		// Or when it ends on one, and skipping months that are too short.
+		{"0 0 0 31W * ?", "2021-09-01T00:00:00Z", []string{
+			"2021-10-29T00:00:00Z", "2021-12-31T00:00:00Z"}},
+
+		// Nth and last day of the week.
+		{"0 0 9 ? * TUE#2", "2021-10-01T00:00:00Z", []string{
+			"2021-10-12T09:00:00Z", "2021-11-09T09:00:00Z", "2021-12-14T09:00:00Z"}},
+		{"0 0 9 ? * 2#5", "2021-10-01T00:00:00Z", []string{
+			"2021-11-30T09:00:00Z", "2022-03-29T09:00:00Z"}},
+		{"0 0 9 ? * 5L", "2021-10-01T00:00:00Z", []string{
+			"2021-10-29T09:00:00Z", "2021-11-26T09:00:00Z"}},
+		{"0 0 9 ? * FRIL", "2021-10-01T00:00:00Z", []string{
+			"2021-10-29T09:00:00Z"}},
+
+		// Years, after which the schedule stops.
+		{"0 0 0 1 1 ? 2030", "2021-10-01T00:00:00Z", []string{
+			"2030-01-01T00:00:00Z", ""}},
+		{"0 0 0 1 1 ? 2030-2031", "2021-10-01T00:00:00Z", []string{
+			"2030-01-01T00:00:00Z", "2031-01-01T00:00:00Z", ""}},
+		{"0 0 0 L 2 ? 2028", "2021-10-01T00:00:00Z", []string{
+			"2028-02-29T00:00:00Z", ""}},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", test.spec, err)
+			continue
+		}
+		next, _ := time.Parse(time.RFC3339, test.from)
+		for _, value := range test.expected {
+			var expected time.Time
+			if value != "" {
+				expected, _ = time.Parse(time.RFC3339, value)
+			}
+			next = schedule.Next(next)
+			if !next.Equal(expected) {
+				t.Errorf("%q from %s: expected %s, got %s", test.spec, test.from, expected, next)
+				break
+			}
+		}
+	}
+}
+
+func TestParseSpecialCharacterErrors(t *testing.T) {
+	tests := []struct {
+		spec Cron
+		err  string
+	}{
+		{"0 0 0 32W * ?", "Day of nearest weekday (32) outside 1-31"},
+		{"0 0 0 0W * ?", "Day of nearest weekday (0) outside 1-31"},
+		{"0 0 0 1-5W * ?", "Nearest weekday needs a single day"},
+		{"0 0 0 5L * ?", "Only L, LW and nW are allowed in the day of month"},
+		{"0 0 0 1#2 * ?", "Only L, LW and nW are allowed in the day of month"},
+		{"0 0 0 ? * TUE#6", "Occurrence (6) must be between 1 and 5"},
+		{"0 0 0 ? * TUE#0", "Occurrence (0) must be between 1 and 5"},
+		{"0 0 0 ? * 1#2#3", "Too many hashes"},
+		{"0 0 0 ? * 8#1", "Day of week (8) above maximum (6)"},
+		{"0 0 0 ? * 7L", "Day of week (7) above maximum (6)"},
+		{"0 0 0 ? * L", "Only d#n and dL are allowed in the day of week"},
+		{"0 0 0 ? * 15W", "Only d#n and dL are allowed in the day of week"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.spec)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.spec)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected an error containing %q, got %q", test.spec, test.err, err)
+		}
+	}
+}
+
+func TestParseHashStable(t *testing.T) {
+	spec := Cron("H H * * *")
+	activations := func(id ID) []time.Time {
+		schedule, err := standardParser.ForTask(id).Parse(spec)
+		if err != nil {
+			t.Fatalf("%q for %s: %v", spec, id, err)
+		}
+		var times []time.Time
+		for next := start; len(times) < 3; {
+			next = schedule.Next(next)
+			times = append(times, next)
+		}
+		return times
+	}
+
+	seen := make(map[time.Time]bool)
+	for i := 0; i < 20; i++ {
+		id := ID(fmt.Sprintf("task-%d", i))
+		first, again := activations(id), activations(id)
+		if !reflect.DeepEqual(first, again) {
+			t.Errorf("%s: expected the same activations every time, got %v and %v", id, first, again)
+		}
+		if first[1].Sub(first[0]) != 24*time.Hour {
+			t.Errorf("%s: expected daily activations, got %v", id, first)
+		}
+		seen[first[0]] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("expected tasks to be spread out, got %d different times for 20 tasks", len(seen))
+	}
+}
+
+func TestExpandHash(t *testing.T) {
+	tests := []struct {
+		field string
+		r     bounds
+		// The values the field may resolve to, whatever the hash.
+		min, max, step uint
+	}{
+		{"H", minutes, 0, 59, 0},
+		{"H", hours, 0, 23, 0},
+		{"H", hashBounds[3], 1, 28, 0},
+		{"H(0-5)", hours, 0, 5, 0},
+		{"h(10-12)", minutes, 10, 12, 0},
+		{"H(3-3)", hours, 3, 3, 0},
+		{"H/15", minutes, 0, 59, 15},
+		{"H(0-29)/10", minutes, 0, 29, 10},
+		{"H(1-12)/4", months, 1, 12, 4},
+	}
+
+	for _, test := range tests {
+		for hash := uint(0); hash < 100; hash++ {
+			expanded, err := expandHash(test.field, test.r, hash)
+			if err != nil {
+				t.Fatalf("%q: unexpected error: %v", test.field, err)
+			}
+			bits, err := getField(expanded, test.r)
+			if err != nil {
+				t.Fatalf("%q resolved to %q: %v", test.field, expanded, err)
+			}
+			var values []uint
+			for v := test.r.min; v <= test.r.max; v++ {
+				if bits&(1<<v) != 0 {
+					values = append(values, v)
+				}
+			}
+			if test.step == 0 && len(values) != 1 {
+				t.Errorf("%q resolved to %q: expected a single value", test.field, expanded)
+			}
+			// Steps start within the first step and run to the end of the range.
+			if test.step > 0 && (values[0] >= test.min+test.step || values[len(values)-1]+test.step <= test.max) {
+				t.Errorf("%q resolved to %q: expected steps of %d over %d-%d", test.field, expanded, test.step, test.min, test.max)
+			}
+			for i, v := range values {
+				if v < test.min || v > test.max {
+					t.Errorf("%q resolved to %q: %d outside %d-%d", test.field, expanded, v, test.min, test.max)
+				}
+				if i > 0 && v-values[i-1] != test.step {
+					t.Errorf("%q resolved to %q: expected steps of %d", test.field, expanded, test.step)
+				}
+			}
+		}
+	}
+}
+
+func TestParseHashErrors(t *testing.T) {
+	tests := []struct {
+		spec Cron
+		err  string
+	}{
+		{"H(0-60) * * * *", "Range of H must lie within 0-59"},
+		{"0 H(5-2) * * *", "Range of H must lie within 0-23"},
+		{"0 0 H(0-28) * *", "Range of H must lie within 1-28"},
+		{"0 0 H(1-29) * *", "Range of H must lie within 1-28"},
+		{"H(0-5 * * * *", "H takes a range as H(a-b)"},
+		{"H0-5 * * * *", "H takes a range as H(a-b)"},
+		{"H/0 * * * *", "Step of range should be a positive number"},
+		{"H/5/2 * * * *", "Too many slashes"},
+	}
+
+	for _, test := range tests {
+		_, err := standardParser.ForTask("task").Parse(test.spec)
+		if err == nil {
+			t.Errorf("%q: expected an error", test.spec)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%q: expected an error containing %q, got %q", test.spec, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"sort"
+	"time"
+)
+
+// DSTPolicy decides what a SpecSchedule does with wall clock times that a
+// daylight saving time transition skips or repeats.
+//
+// On a spring-forward day, times inside the gap (e.g. 02:30 when clocks jump
+// from 02:00 to 03:00) don't exist. On a fall-back day, times inside the
+// overlap (e.g. 01:30 when clocks go back from 02:00 to 01:00) happen twice.
+// Whatever the policy, a time that happens twice runs once, at its first
+// occurrence after the time passed to Next.
+type DSTPolicy int
+
+const (
+	// DSTRunOnce runs all times skipped by a gap once, at the instant the clocks
+	// jump forward.
+	DSTRunOnce DSTPolicy = iota
+	// DSTSkip drops times skipped by a gap, so they don't run that day.
+	DSTSkip
+)
+
+// DSTHandler is an optional interface, see ScheduleOptions, that chooses how a
+// task's cron schedule treats daylight saving time transitions. Tasks without
+// one use DSTRunOnce.
+type DSTHandler interface {
+	DSTPolicy() DSTPolicy
+}
+
+// dstPolicyOf returns the DST policy of the task.
+func dstPolicyOf(task Task) DSTPolicy {
+	for _, v := range optionSources(task) {
+		if d, ok := v.(DSTHandler); ok {
+			return d.DSTPolicy()
+		}
+	}
+	return DSTRunOnce
+}
+
+// withDSTPolicy returns schedule set to follow policy. Schedules that don't
+// work in wall clock time are returned as they are.
+func withDSTPolicy(schedule Schedule, policy DSTPolicy) Schedule {
+	switch schedule := schedule.(type) {
+	case *SpecSchedule:
+		if schedule.DST == policy {
+			return schedule
+		}
+		s := *schedule
+		s.DST = policy
+		return &s
+	case UnionSchedule:
+		union := make(UnionSchedule, len(schedule))
+		for i, s := range schedule {
+			union[i] = withDSTPolicy(s, policy)
+		}
+		return union
+	case ExcludeSchedule:
+		return ExcludeSchedule{
+			Include: withDSTPolicy(schedule.Include, policy),
+			Exclude: withDSTPolicy(schedule.Exclude, policy),
+		}
+	}
+	return schedule
+}
+
+// wallClock returns the wall clock reading of t as a UTC time, which is free
+// of DST transitions.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// instants returns, in order, the instants at which the wall clock in loc
+// reads wall. There are none inside a DST gap and two inside an overlap.
+func instants(wall time.Time, loc *time.Location) []time.Time {
+	var found []time.Time
+	for _, probe := range []time.Time{wall.Add(-24 * time.Hour), wall.Add(24 * time.Hour)} {
+		_, offset := probe.In(loc).Zone()
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if !wallClock(t).Equal(wall) {
+			continue
+		}
+		if len(found) == 0 || !found[0].Equal(t) {
+			found = append(found, t)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Before(found[j]) })
+	return found
+}
+
+// gapEnd returns the instant the clocks in loc jump forward over wall, which
+// lies inside a DST gap.
+func gapEnd(wall time.Time, loc *time.Location) time.Time {
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+	// Reading wall with the offset after the gap lands before the jump, and
+	// with the offset before it lands after the jump.
+	lo := wall.Add(-time.Duration(after) * time.Second).Unix()
+	hi := wall.Add(-time.Duration(before) * time.Second).Unix()
+	for lo+1 < hi {
+		mid := lo + (hi-lo)/2
+		if _, offset := time.Unix(mid, 0).In(loc).Zone(); offset == after {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return time.Unix(hi, 0).In(loc)
+}
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDSTNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// In New York clocks jump from 02:00 EST to 03:00 EDT on 2021-03-14, and go
+	// back from 02:00 EDT to 01:00 EST on 2021-11-07. In Berlin they jump from
+	// 02:00 CET to 03:00 CEST on 2021-03-28, and go back from 03:00 CEST to
+	// 02:00 CET on 2021-10-31.
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		spec     Cron
+		policy   DSTPolicy
+		from     string
+		expected []string
+	}{
+		{"New York gap, run once", newYork, "0 30 2 * * *", DSTRunOnce,
+			"2021-03-13T12:00:00-05:00", []string{
+				"2021-03-14T03:00:00-04:00",
+				"2021-03-15T02:30:00-04:00",
+			}},
+		{"New York gap, skip", newYork, "0 30 2 * * *", DSTSkip,
+			"2021-03-13T12:00:00-05:00", []string{
+				"2021-03-15T02:30:00-04:00",
+			}},
+		{"New York gap, every quarter hour, run once", newYork, "0 */15 2 * * *", DSTRunOnce,
+			"2021-03-14T01:50:00-05:00", []string{
+				"2021-03-14T03:00:00-04:00",
+				"2021-03-15T02:00:00-04:00",
+			}},
+		{"New York gap, every quarter hour, skip", newYork, "0 */15 2 * * *", DSTSkip,
+			"2021-03-14T01:50:00-05:00", []string{
+				"2021-03-15T02:00:00-04:00",
+			}},
+		{"New York outside the gap", newYork, "0 0 3 * * *", DSTSkip,
+			"2021-03-14T01:50:00-05:00", []string{
+				"2021-03-14T03:00:00-04:00",
+			}},
+		{"New York overlap", newYork, "0 30 1 * * *", DSTRunOnce,
+			"2021-11-06T12:00:00-04:00", []string{
+				"2021-11-07T01:30:00-04:00",
+				"2021-11-08T01:30:00-05:00",
+			}},
+		{"New York overlap, skip", newYork, "0 30 1 * * *", DSTSkip,
+			"2021-11-06T12:00:00-04:00", []string{
+				"2021-11-07T01:30:00-04:00",
+				"2021-11-08T01:30:00-05:00",
+			}},
+		{"New York overlap, from its second pass", newYork, "0 30 1 * * *", DSTRunOnce,
+			"2021-11-07T01:10:00-05:00", []string{
+				"2021-11-07T01:30:00-05:00",
+				"2021-11-08T01:30:00-05:00",
+			}},
+		{"New York overlap, hourly", newYork, "0 0 * * * *", DSTRunOnce,
+			"2021-11-07T00:30:00-04:00", []string{
+				"2021-11-07T01:00:00-04:00",
+				"2021-11-07T02:00:00-05:00",
+			}},
+
+		{"Berlin gap, run once", berlin, "0 30 2 * * *", DSTRunOnce,
+			"2021-03-27T12:00:00+01:00", []string{
+				"2021-03-28T03:00:00+02:00",
+				"2021-03-29T02:30:00+02:00",
+			}},
+		{"Berlin gap, skip", berlin, "0 30 2 * * *", DSTSkip,
+			"2021-03-27T12:00:00+01:00", []string{
+				"2021-03-29T02:30:00+02:00",
+			}},
+		{"Berlin overlap", berlin, "0 30 2 * * *", DSTRunOnce,
+			"2021-10-30T12:00:00+02:00", []string{
+				"2021-10-31T02:30:00+02:00",
+				"2021-11-01T02:30:00+01:00",
+			}},
+		{"Berlin overlap, skip", berlin, "0 30 2 * * *", DSTSkip,
+			"2021-10-30T12:00:00+02:00", []string{
+				"2021-10-31T02:30:00+02:00",
+				"2021-11-01T02:30:00+01:00",
+			}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schedule, err := Parse(test.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			schedule = withDSTPolicy(schedule, test.policy)
+			from, err := time.Parse(time.RFC3339, test.from)
+			if err != nil {
+				t.Fatal(err)
+			}
+			next := from.In(test.loc)
+			for _, value := range test.expected {
+				expected, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					t.Fatal(err)
+				}
+				next = schedule.Next(next)
+				if !next.Equal(expected) {
+					t.Fatalf("expected %s, got %s", expected, next)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package scheduler
 
 import (
+	"encoding/json"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"strings"
 	"sync"
 	"time"
@@ -10,15 +12,31 @@ import (
 type EntryStatus string
 
 const (
-	Stopped    EntryStatus = "Stopped"
-	Running    EntryStatus = "Running"
-	PendingRun EntryStatus = "Pending Run"
-	Failing    EntryStatus = "Failing"
-	Success    EntryStatus = "Success"
+	Stopped     EntryStatus = "Stopped"
+	Running     EntryStatus = "Running"
+	PendingRun  EntryStatus = "Pending Run"
+	Failing     EntryStatus = "Failing"
+	Success     EntryStatus = "Success"
+	NotStarted  EntryStatus = "Not Started" // before the task's start date
+	Expired     EntryStatus = "Expired"     // past the task's end date
+	TimedOut    EntryStatus = "Timed Out"   // the run was cancelled after its timeout
+	Interrupted EntryStatus = "Interrupted" // the run was still going when the scheduler shut down
+	Queued      EntryStatus = "Queued"      // due, but waiting for a free slot
+	Skipped     EntryStatus = "Skipped"     // dropped for falling on a calendar day
+	Missed      EntryStatus = "Missed"      // not run for starting too late, see MisfirePolicy
 )
 
 // Entry consists of a schedule and the func to execute on that schedule.
 type Entry struct {
+	// ID identifies the entry. It is the TaskID for the task's own schedule,
+	// unique per run time for one-shot entries, see Atmo.ScheduleOnce, and
+	// parent/child for the sub-tasks of a task.
+	ID ID `json:"id"`
+
+	// Parent is the ID of the entry whose runs run this one, for sub-tasks.
+	// Sub-task entries are never scheduled on their own.
+	Parent ID `json:"parent,omitempty"`
+
 	// The schedule on which this job should be run.
 	Schedule Schedule `json:"-"`
 
@@ -37,6 +55,16 @@ type Entry struct {
 
 	Status EntryStatus `json:"-"`
 
+	// Paused entries are kept but never scheduled until they are resumed.
+	Paused bool `json:"paused"`
+
+	// StopReason explains why a task with StopOnFailure set was paused after a
+	// failed run. It is cleared when the entry is resumed.
+	StopReason string `json:"stop_reason,omitempty"`
+
+	// timedOut is closed once the last timed out run has returned.
+	timedOut chan struct{}
+
 	History       []*TaskHistory      `json:"history"` // time | status
 	Errors        map[time.Time]error `json:"errors"`
 	*sync.RWMutex `json:"-"`
@@ -46,10 +74,7 @@ func (e Entry) MarshalToBSON() (bson.M, error) {
 	var history = bson.A{}
 	var errors = bson.A{}
 	for _, h := range e.History {
-		history = append(history, bson.M{h.ExecutionTime.String(): bson.D{
-			{Key: "logs", Value: h.Logs},
-			{Key: "status", Value: h.Status},
-		}})
+		history = append(history, bson.M{h.ExecutionTime.String(): historyToBSON(h)})
 	}
 	for key, er := range e.Errors {
 		errors = append(errors, bson.M{key.String(): bson.D{
@@ -62,6 +87,21 @@ func (e Entry) MarshalToBSON() (bson.M, error) {
 	}, nil
 }
 
+// MarshalStateToBSON returns the fields of the entry that are overwritten,
+// rather than accumulated, on every update.
+func (e Entry) MarshalStateToBSON() bson.M {
+	state := bson.M{
+		"paused":      e.Paused,
+		"stop_reason": e.StopReason,
+		"prev":        e.Prev,
+		"task_id":     e.Task.TaskID(),
+	}
+	if once, ok := e.Schedule.(OnceSchedule); ok {
+		state["once_at"] = once.At
+	}
+	return state
+}
+
 func UnmarshalBSON(data bson.M, e *Entry) error {
 	e.Lock()
 	defer e.Unlock()
@@ -75,15 +115,17 @@ func UnmarshalBSON(data bson.M, e *Entry) error {
 					if err != nil {
 						return err
 					}
-					status := v.(bson.M)["status"].(string)
-					logs := v.(bson.M)["logs"].(string)
-					e.History = append(e.History, &TaskHistory{
-						ExecutionTime: ti,
-						Status:        EntryStatus(status),
-						Logs:          logs,
-					})
+					e.History = append(e.History, historyFromBSON(ti, v.(bson.M)))
 				}
 			}
+		case "paused":
+			e.Paused, _ = val.(bool)
+		case "prev":
+			if prev, ok := val.(primitive.DateTime); ok {
+				e.Prev = prev.Time()
+			}
+		case "stop_reason":
+			e.StopReason, _ = val.(string)
 		case "errors":
 			for _, a := range val.(bson.A) {
 				for k, v := range a.(bson.M) {
@@ -99,8 +141,118 @@ func UnmarshalBSON(data bson.M, e *Entry) error {
 	return nil
 }
 
+// historyToBSON returns the stored fields of a history row, including the rows
+// of its mapped sub-task instances.
+func historyToBSON(h *TaskHistory) bson.D {
+	var children = bson.A{}
+	for _, child := range h.Children {
+		children = append(children, append(historyToBSON(child),
+			bson.E{Key: "execution_time", Value: child.ExecutionTime},
+			bson.E{Key: "sub_task", Value: child.SubTask},
+		))
+	}
+	return bson.D{
+		{Key: "logs", Value: h.Logs},
+		{Key: "status", Value: h.Status},
+		{Key: "manual", Value: h.Manual},
+		{Key: "execution_date", Value: h.ExecutionDate},
+		{Key: "attempt", Value: h.Attempt},
+		{Key: "outputs", Value: h.Outputs.stored()},
+		{Key: "params", Value: h.Params},
+		{Key: "children", Value: children},
+	}
+}
+
+// historyFromBSON returns the history row stored as v, which ran at ti.
+func historyFromBSON(ti time.Time, v bson.M) *TaskHistory {
+	status, _ := v["status"].(string)
+	logs, _ := v["logs"].(string)
+	manual, _ := v["manual"].(bool)
+	executionDate, _ := v["execution_date"].(primitive.DateTime)
+	attempt, _ := v["attempt"].(int32)
+	var outputs Outputs
+	if stored, ok := v["outputs"].(bson.M); ok {
+		outputs = make(Outputs, len(stored))
+		for name, raw := range stored {
+			text, _ := raw.(string)
+			outputs[name] = json.RawMessage(text)
+		}
+	}
+	var params Params
+	if stored, ok := v["params"].(bson.M); ok {
+		params = make(Params, len(stored))
+		for name, value := range stored {
+			params[name], _ = value.(string)
+		}
+	}
+	h := &TaskHistory{
+		ExecutionTime: ti,
+		Status:        EntryStatus(status),
+		Logs:          logs,
+		Manual:        manual,
+		ExecutionDate: executionDate.Time(),
+		Attempt:       int(attempt),
+		Outputs:       outputs,
+		Params:        params,
+	}
+	children, _ := v["children"].(bson.A)
+	for _, c := range children {
+		child, ok := c.(bson.M)
+		if !ok {
+			continue
+		}
+		executionTime, _ := child["execution_time"].(primitive.DateTime)
+		subTask, _ := child["sub_task"].(string)
+		row := historyFromBSON(executionTime.Time(), child)
+		row.SubTask = ID(subTask)
+		h.Children = append(h.Children, row)
+	}
+	return h
+}
+
 func (e *Entry) ChangeStatus(s EntryStatus) {
 	e.Lock()
 	defer e.Unlock()
 	e.Status = s
 }
+
+// nextAfter returns the entry's next activation time after t, honouring the
+// start and end dates of the task.
+func (e *Entry) nextAfter(t time.Time) time.Time {
+	return nextActivation(e.Schedule, e.Task.ScheduleOptions(), t)
+}
+
+// displayStatus returns the status to report for the entry at now. Entries
+// waiting for their next run are reported as NotStarted before the task's
+// start date and as Expired once no run is left before its end date.
+func (e *Entry) displayStatus(now time.Time) EntryStatus {
+	if e.Status != PendingRun {
+		return e.Status
+	}
+	opts := e.Task.ScheduleOptions()
+	switch {
+	case now.Before(opts.StartDate()):
+		return NotStarted
+	case e.Next.IsZero() && !opts.EndDate().IsZero():
+		return Expired
+	}
+	return e.Status
+}
+
+// isPaused reports whether the entry is paused.
+func (e *Entry) isPaused() bool {
+	e.RLock()
+	defer e.RUnlock()
+	return e.Paused
+}
+
+// settleStatus sets the status the entry rests in after a run. Paused entries
+// always rest as Stopped.
+func (e *Entry) settleStatus(s EntryStatus) {
+	e.Lock()
+	defer e.Unlock()
+	if e.Paused {
+		s = Stopped
+	}
+	e.Status = s
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSubTasksFailed is wrapped by the error of a parent run whose sub-tasks
+// did not satisfy its SubTaskPolicy.
+var ErrSubTasksFailed = errors.New("sub-tasks failed")
+
+// SubTaskResult is the outcome of one sub-task, or mapped instance, of a
+// parent run.
+type SubTaskResult struct {
+	ID      ID          // the entry ID the sub-task ran under
+	Index   int         // the MapIndex of a mapped instance, or -1
+	Key     string      // the MapKey of a mapped instance
+	Status  EntryStatus // the status of its last attempt
+	Outputs Outputs     // the outputs of its last attempt
+	Err     error       // nil if it succeeded
+}
+
+// SubTaskPolicy decides the outcome of a parent run from those of its
+// sub-tasks, once the parent's own Run has succeeded.
+type SubTaskPolicy int
+
+const (
+	// SubTasksAllSucceed fails the parent run if any sub-task failed.
+	SubTasksAllSucceed SubTaskPolicy = iota
+	// SubTasksAnySucceed fails the parent run only if every sub-task failed.
+	SubTasksAnySucceed
+	// SubTasksIgnoreFailures keeps the parent run successful whatever its
+	// sub-tasks did.
+	SubTasksIgnoreFailures
+)
+
+// SubTaskPolicer is an optional interface, see ScheduleOptions, that chooses
+// the SubTaskPolicy of a task's runs. Without one, SubTasksAllSucceed applies.
+type SubTaskPolicer interface {
+	SubTaskPolicy() SubTaskPolicy
+}
+
+// SubTasksCompleter is implemented by a Task to be told how its sub-tasks did
+// once all of them have finished. err is the outcome the task's SubTaskPolicy
+// gave the parent run, and the error returned replaces it.
+type SubTasksCompleter interface {
+	OnSubTasksComplete(results []SubTaskResult, err error) error
+}
+
+// subTaskPolicyOf returns the SubTaskPolicy of the task.
+func subTaskPolicyOf(task Task) SubTaskPolicy {
+	for _, v := range optionSources(task) {
+		if p, ok := v.(SubTaskPolicer); ok {
+			return p.SubTaskPolicy()
+		}
+	}
+	return SubTasksAllSucceed
+}
+
+// outcome returns the error the parent run fails with under the policy, or
+// nil if it succeeds.
+func (p SubTaskPolicy) outcome(results []SubTaskResult) error {
+	var failed []SubTaskResult
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	switch {
+	case len(failed) == 0, p == SubTasksIgnoreFailures:
+		return nil
+	case p == SubTasksAnySucceed && len(failed) < len(results):
+		return nil
+	}
+	return fmt.Errorf("%w: %d of %d, %s: %v", ErrSubTasksFailed, len(failed), len(results), failed[0].ID, failed[0].Err)
+}
+
+// fanIn settles the outcome of a parent run from the results of its sub-tasks,
+// and marks row, the history row of the parent run, as failed if it fails.
+func (c *Atmo) fanIn(e *Entry, row *TaskHistory, results []SubTaskResult) error {
+	err := subTaskPolicyOf(e.Task).outcome(results)
+	if h, ok := e.Task.(SubTasksCompleter); ok {
+		err = h.OnSubTasksComplete(results, err)
+	}
+	if err == nil {
+		return nil
+	}
+	c.logf("[%s] failed on its sub-tasks: %s", e.Task.TaskID().ToString(), err)
+	e.Lock()
+	row.Status = Failing
+	e.Errors[row.ExecutionTime] = err
+	e.Unlock()
+	e.settleStatus(Failing)
+	return err
+}
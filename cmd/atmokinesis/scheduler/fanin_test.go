@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubTaskPolicyOutcome(t *testing.T) {
+	ok := SubTaskResult{ID: "ok", Status: Success}
+	failed := SubTaskResult{ID: "failed", Status: Failing, Err: errBoom}
+	tests := []struct {
+		policy  SubTaskPolicy
+		results []SubTaskResult
+		fails   bool
+	}{
+		{SubTasksAllSucceed, []SubTaskResult{ok, ok}, false},
+		{SubTasksAllSucceed, []SubTaskResult{ok, failed}, true},
+		{SubTasksAllSucceed, []SubTaskResult{failed, failed}, true},
+		{SubTasksAnySucceed, []SubTaskResult{ok, ok}, false},
+		{SubTasksAnySucceed, []SubTaskResult{ok, failed}, false},
+		{SubTasksAnySucceed, []SubTaskResult{failed, failed}, true},
+		{SubTasksIgnoreFailures, []SubTaskResult{ok, failed}, false},
+		{SubTasksIgnoreFailures, []SubTaskResult{failed, failed}, false},
+		{SubTasksAllSucceed, nil, false},
+	}
+
+	for _, test := range tests {
+		err := test.policy.outcome(test.results)
+		if fails := err != nil; fails != test.fails {
+			t.Errorf("policy %d, %v: expected failure %t, got %v", test.policy, test.results, test.fails, err)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrSubTasksFailed) {
+			t.Errorf("policy %d, %v: expected ErrSubTasksFailed, got %v", test.policy, test.results, err)
+		}
+	}
+}
+
+// completerTask is a testTask told how its sub-tasks did, which fails with
+// override if set and otherwise keeps the outcome of its policy.
+type completerTask struct {
+	testTask
+	results  []SubTaskResult
+	err      error
+	override error
+}
+
+func (t *completerTask) OnSubTasksComplete(results []SubTaskResult, err error) error {
+	t.results, t.err = results, err
+	if t.override != nil {
+		return t.override
+	}
+	return err
+}
+
+func TestFanIn(t *testing.T) {
+	subTask := func(id ID, err error) Task {
+		return &testTask{id: id, run: func(ctx Context) error {
+			if err := ctx.SetOutput("by", string(id)); err != nil {
+				return err
+			}
+			return err
+		}}
+	}
+	tests := []struct {
+		name     string
+		policy   SubTaskPolicy
+		failures int
+		override error
+		expected EntryStatus
+	}{
+		{"all succeed", SubTasksAllSucceed, 0, nil, Success},
+		{"all succeed, one fails", SubTasksAllSucceed, 1, nil, Failing},
+		{"any succeed, one fails", SubTasksAnySucceed, 1, nil, Success},
+		{"any succeed, all fail", SubTasksAnySucceed, 2, nil, Failing},
+		{"ignore failures", SubTasksIgnoreFailures, 2, nil, Success},
+		{"completer overrides", SubTasksIgnoreFailures, 0, errBoom, Failing},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewWithLocation(time.UTC, NewFakeClock(start))
+			var subTasks []Task
+			for i, id := range []ID{"first", "second"} {
+				var err error
+				if i < test.failures {
+					err = errBoom
+				}
+				subTasks = append(subTasks, subTask(id, err))
+			}
+			task := &completerTask{testTask: testTask{
+				id:       "parent",
+				cron:     "@daily",
+				opts:     NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).WithSubTaskPolicy(test.policy),
+				subTasks: subTasks,
+			}, override: test.override}
+			if err := c.AddTask(task.Schedule(), task); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Trigger(task.TaskID()); err != nil {
+				t.Fatal(err)
+			}
+			settle(t, c)
+
+			e := c.entries[0]
+			if len(e.History) != 1 || e.History[0].Status != test.expected {
+				t.Fatalf("expected a single %s row, got %v", test.expected, e.History)
+			}
+			if err := e.Errors[e.History[0].ExecutionTime]; (err != nil) != (test.expected == Failing) {
+				t.Errorf("expected an error to be recorded for a failed run only, got %v", err)
+			}
+
+			if policyFailed := test.expected == Failing && test.override == nil; (task.err != nil) != policyFailed {
+				t.Errorf("expected the completer to be told failure %t, got %v", policyFailed, task.err)
+			}
+			if len(task.results) != 2 {
+				t.Fatalf("expected the results of both sub-tasks, got %v", task.results)
+			}
+			for i, r := range task.results {
+				failed := i < test.failures
+				if (r.Err != nil) != failed || r.Index != -1 {
+					t.Errorf("result %d: expected failure %t, got %+v", i, failed, r)
+				}
+				if by, _ := r.Outputs.String("by"); by != string(r.ID[len("parent/"):]) {
+					t.Errorf("result %d: expected the outputs of %s, got %q", i, r.ID, by)
+				}
+			}
+		})
+	}
+}
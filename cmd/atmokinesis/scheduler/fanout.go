@@ -0,0 +1,133 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MapLimiter is an optional interface, see ScheduleOptions, that caps how many
+// of a task's sub-tasks, including the instances spawned by
+// Context.MapSubTask, run at once when the sub-tasks are parallel. Zero means
+// no cap.
+type MapLimiter interface {
+	MapConcurrency() int
+}
+
+// mapConcurrencyOf returns how many of the task's parallel sub-tasks may run
+// at once, or zero for no cap.
+func mapConcurrencyOf(task Task) int {
+	for _, v := range optionSources(task) {
+		if m, ok := v.(MapLimiter); ok {
+			return m.MapConcurrency()
+		}
+	}
+	return 0
+}
+
+// mappedSubTask is one instance of a sub-task template spawned by
+// Context.MapSubTask, for the item at index among the keys it was given.
+type mappedSubTask struct {
+	template Task
+	index    int
+	key      string
+}
+
+// mappedSubTasksOf returns the instances spawned through ctx.
+func mappedSubTasksOf(ctx Context) []mappedSubTask {
+	bc, ok := ctx.(*BaseContext)
+	if !ok {
+		return nil
+	}
+	bc.RLock()
+	defer bc.RUnlock()
+	return *bc.mapped
+}
+
+// entry returns a new entry for the instance, as a child of parent. It is
+// never scheduled, only run by its parent, and isn't kept after the run.
+func (m mappedSubTask) entry(parent *Entry) *Entry {
+	return newChildEntry(ID(fmt.Sprintf("%s[%s]", childID(parent, m.template), m.key)), parent, m.template)
+}
+
+// runSubTasks runs the sub-tasks of a successful run of the entry, followed
+// by the instances it spawned, one at a time unless the task's sub-tasks are
+// parallel, and waits for all of them. Each of them is run as req. The history
+// rows of the instances are grouped under row, the history row of the parent
+// run. Under a mapped instance, the sub-tasks get entries for the run only,
+// like the instance itself, so that mapping over new keys doesn't pile up
+// entries, and the history rows of all of them are grouped under row.
+func (c *Atmo) runSubTasks(e *Entry, row *TaskHistory, req runRequest, stream chan interface{}, instances []mappedSubTask) []SubTaskResult {
+	isParallel, subTasks := e.Task.SubTasks()
+	limit := 1
+	if isParallel {
+		limit = mapConcurrencyOf(e.Task)
+	}
+	var slots chan struct{}
+	if limit > 0 {
+		slots = make(chan struct{}, limit)
+	}
+
+	var (
+		children []*Entry
+		requests []runRequest
+	)
+	for _, subTask := range subTasks {
+		if req.transient {
+			children = append(children, newChildEntry(childID(e, subTask), e, subTask))
+		} else {
+			children = append(children, c.childEntry(e, subTask))
+		}
+		requests = append(requests, req)
+	}
+	grouped := len(children)
+	if req.transient {
+		grouped = 0
+	}
+	for i := range instances {
+		children = append(children, instances[i].entry(e))
+		r := req
+		r.item = &instances[i]
+		requests = append(requests, r)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]SubTaskResult, len(children))
+	for i := range children {
+		results[i] = SubTaskResult{ID: children[i].ID, Index: -1, Status: Interrupted, Err: errInterrupted}
+		if item := requests[i].item; item != nil {
+			results[i].Index, results[i].Key = item.index, item.key
+		}
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-c.ctx.Done():
+			}
+		}
+		if c.ctx.Err() != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if slots != nil {
+				defer func() { <-slots }()
+			}
+			h, err := c.runWithRecovery(children[i], stream, requests[i])
+			results[i].Err = err
+			if h != nil {
+				results[i].Status, results[i].Outputs = h.Status, h.Outputs
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	e.Lock()
+	defer e.Unlock()
+	for _, se := range children[grouped:] {
+		for _, h := range se.History {
+			h.SubTask = se.ID
+			row.Children = append(row.Children, h)
+		}
+	}
+	return results
+}
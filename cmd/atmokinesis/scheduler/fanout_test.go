@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMapSubTask(t *testing.T) {
+	c := NewWithLocation(time.UTC, NewFakeClock(start))
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]int)
+	)
+	leaf := &testTask{id: "leaf", run: func(ctx Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen["leaf"]++
+		return nil
+	}}
+	template := &testTask{
+		id: "item",
+		run: func(ctx Context) error {
+			batch, err := ctx.ParentOutputs().String("batch")
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			seen[ctx.MapKey()+":"+batch] = ctx.MapIndex()
+			return nil
+		},
+		subTasks: []Task{leaf},
+	}
+	keys := []string{"a", "b", "c"}
+	task := &testTask{
+		id:   "parent",
+		cron: "@daily",
+		run: func(ctx Context) error {
+			ctx.MapSubTask(template, keys...)
+			return ctx.SetOutput("batch", "42")
+		},
+	}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Trigger(task.TaskID()); err != nil {
+		t.Fatal(err)
+	}
+	settle(t, c)
+
+	for i, key := range keys {
+		if index, ok := seen[key+":42"]; !ok || index != i {
+			t.Errorf("expected instance %s to run at index %d with the parent's outputs, got %d, %t", key, i, index, ok)
+		}
+	}
+	if seen["leaf"] != len(keys) {
+		t.Errorf("expected the sub-task to run once for each instance, got %d", seen["leaf"])
+	}
+
+	row := c.entries[0].History[0]
+	if row.Status != Success || len(row.Children) != len(keys) {
+		t.Fatalf("expected a successful run with %d instance rows, got %s with %d", len(keys), row.Status, len(row.Children))
+	}
+	for i, child := range row.Children {
+		if expected := ID("parent/item[" + keys[i] + "]"); child.SubTask != expected {
+			t.Errorf("row %d: expected the instance %s, got %s", i, expected, child.SubTask)
+		}
+		// The rows of an instance's sub-tasks are grouped under its own.
+		if len(child.Children) != 1 || child.Children[0].SubTask != ID("parent/item["+keys[i]+"]/leaf") {
+			t.Errorf("row %d: expected the row of its sub-task, got %v", i, child.Children)
+		}
+	}
+
+	// Neither the instances nor their sub-tasks are kept, whatever the keys.
+	keys = []string{"d", "e"}
+	if err := c.Trigger(task.TaskID()); err != nil {
+		t.Fatal(err)
+	}
+	settle(t, c)
+	for _, e := range c.Children() {
+		if strings.Contains(string(e.ID), "[") {
+			t.Errorf("expected no entry to be kept for a mapped instance, got %s", e.ID)
+		}
+	}
+}
+
+func TestMapConcurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		parallel bool
+		limit    int
+		expected int
+	}{
+		{"sequential", false, 0, 1},
+		{"sequential despite a limit", false, 3, 1},
+		{"limited", true, 2, 2},
+		{"unlimited", true, 0, 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewWithLocation(time.UTC, NewFakeClock(start))
+			started := make(chan string, 10)
+			release := make(chan struct{})
+			template := &testTask{id: "item", run: func(ctx Context) error {
+				started <- ctx.MapKey()
+				<-release
+				return nil
+			}}
+			task := &testTask{
+				id:   "parent",
+				cron: "@daily",
+				opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).WithMapConcurrency(test.limit),
+				run: func(ctx Context) error {
+					ctx.MapSubTask(template, "a", "b", "c", "d", "e")
+					return nil
+				},
+				parallel: test.parallel,
+			}
+			if err := c.AddTask(task.Schedule(), task); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Trigger(task.TaskID()); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < test.expected; i++ {
+				select {
+				case <-started:
+				case <-time.After(wait):
+					t.Fatalf("expected %d instances to run at once, got %d", test.expected, i)
+				}
+			}
+			select {
+			case key := <-started:
+				t.Errorf("expected at most %d instances to run at once, %s started too", test.expected, key)
+			case <-time.After(quiet):
+			}
+			close(release)
+			settle(t, c)
+			if row := c.entries[0].History[0]; len(row.Children) != 5 {
+				t.Errorf("expected every instance to run, got %d", len(row.Children))
+			}
+		})
+	}
+}
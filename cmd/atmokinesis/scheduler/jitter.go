@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"hash/fnv"
+	"strconv"
+	"time"
+)
+
+// Jitterer is an optional interface, see ScheduleOptions, that delays each run
+// by up to Jitter, so tasks on the same schedule don't all start at once. The
+// delay is worked out from the TaskID and the activation time, so it is the
+// same every time the schedule is computed. Jitter should be shorter than the
+// time between activations.
+type Jitterer interface {
+	Jitter() time.Duration
+}
+
+// jitterOf returns the maximum run delay of the task, or zero if it has none.
+func jitterOf(task Task) time.Duration {
+	for _, v := range optionSources(task) {
+		if j, ok := v.(Jitterer); ok {
+			return j.Jitter()
+		}
+	}
+	return 0
+}
+
+// jitterSchedule delays every activation of a schedule by a fixed, but
+// pseudo-random, amount below max.
+type jitterSchedule struct {
+	Schedule
+	id  ID
+	max time.Duration
+}
+
+// withJitter returns schedule delayed by the task's jitter.
+func withJitter(schedule Schedule, task Task) Schedule {
+	max := jitterOf(task)
+	if max <= 0 {
+		return schedule
+	}
+	return &jitterSchedule{Schedule: schedule, id: task.TaskID(), max: max}
+}
+
+// Next returns the first delayed activation after t.
+func (s *jitterSchedule) Next(t time.Time) time.Time {
+	// Activations up to max before t may still be delayed past it.
+	for next := s.Schedule.Next(t.Add(-s.max)); !next.IsZero(); next = s.Schedule.Next(next) {
+		if delayed := next.Add(s.delay(next)); delayed.After(t) {
+			return delayed
+		}
+	}
+	return time.Time{}
+}
+
+// delay returns the delay of the activation at t.
+func (s *jitterSchedule) delay(t time.Time) time.Duration {
+	h := fnv.New64a()
+	h.Write([]byte(s.id))
+	h.Write([]byte(strconv.FormatInt(t.Unix(), 10)))
+	delay := time.Duration(h.Sum64() % uint64(s.max))
+	if s.max >= time.Second {
+		delay = delay.Truncate(time.Second)
+	}
+	return delay
+}
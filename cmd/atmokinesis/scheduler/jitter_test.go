@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterDelay(t *testing.T) {
+	tests := []struct {
+		max   time.Duration
+		whole time.Duration
+	}{
+		{10 * time.Minute, time.Second},
+		{90 * time.Second, time.Second},
+		{time.Second, time.Second},
+		{500 * time.Millisecond, 0},
+	}
+
+	for _, test := range tests {
+		s := &jitterSchedule{id: "report", max: test.max}
+		other := &jitterSchedule{id: "other", max: test.max}
+		differs := false
+		for i := 0; i < 100; i++ {
+			at := start.Add(time.Duration(i) * time.Hour)
+			delay := s.delay(at)
+			if delay < 0 || delay >= test.max {
+				t.Errorf("max %s at %s: delay %s outside [0, %s)", test.max, at, delay, test.max)
+			}
+			if test.whole > 0 && delay%test.whole != 0 {
+				t.Errorf("max %s at %s: expected whole seconds, got %s", test.max, at, delay)
+			}
+			if again := s.delay(at); again != delay {
+				t.Errorf("max %s at %s: expected the same delay every time, got %s and %s", test.max, at, delay, again)
+			}
+			if other.delay(at) != delay {
+				differs = true
+			}
+		}
+		if !differs && test.max > time.Second {
+			t.Errorf("max %s: expected tasks to be delayed differently", test.max)
+		}
+	}
+}
+
+func TestJitterNext(t *testing.T) {
+	hourly, err := ParseStandard("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	max := 10 * time.Minute
+	s := &jitterSchedule{Schedule: hourly, id: "report", max: max}
+
+	// The activation at start is delayed past it, so it is the first run.
+	activation := start
+	for next, i := start, 0; i < 48; i++ {
+		next = s.Next(next)
+		delayed := activation.Add(s.delay(activation))
+		if !next.Equal(delayed) {
+			t.Fatalf("expected the run of %s at %s, got %s", activation, delayed, next)
+		}
+		if delay := next.Sub(activation); delay < 0 || delay >= max {
+			t.Errorf("run of %s delayed by %s, outside [0, %s)", activation, delay, max)
+		}
+		activation = activation.Add(time.Hour)
+	}
+}
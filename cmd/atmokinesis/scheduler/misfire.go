@@ -0,0 +1,40 @@
+package scheduler
+
+import (
+	"time"
+)
+
+// MisfirePolicy decides what happens to the runs of a task that start later
+// than its misfire threshold, e.g. because the process was suspended. Every
+// slot missed is handled, however many there are.
+type MisfirePolicy int
+
+const (
+	// MisfireFireOnce runs the most recent missed slot once and records the
+	// ones before it as Missed.
+	MisfireFireOnce MisfirePolicy = iota
+	// MisfireSkip runs none of the missed slots and records them all as Missed.
+	MisfireSkip
+	// MisfireFireAll runs every missed slot, oldest first, like Atmo.Backfill
+	// but without its RescueLimit.
+	MisfireFireAll
+)
+
+// Misfirer is an optional interface, see ScheduleOptions, that handles runs
+// that start more than MisfireThreshold after their slot according to
+// MisfirePolicy. Tasks without a threshold run late slots once and drop the
+// others.
+type Misfirer interface {
+	MisfireThreshold() time.Duration
+	MisfirePolicy() MisfirePolicy
+}
+
+// misfireOf returns the misfire threshold and policy of the task.
+func misfireOf(task Task) (time.Duration, MisfirePolicy) {
+	for _, v := range optionSources(task) {
+		if m, ok := v.(Misfirer); ok {
+			return m.MisfireThreshold(), m.MisfirePolicy()
+		}
+	}
+	return 0, MisfireFireOnce
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMisfirePolicy(t *testing.T) {
+	// Every minute, two hours late: the slots of 00:01 to 02:00 are missed.
+	late := 2 * time.Hour
+	slots := 120
+	tests := []struct {
+		name   string
+		policy MisfirePolicy
+		// How many of the missed slots are recorded as Missed, the rest run.
+		missed int
+	}{
+		{"fire once", MisfireFireOnce, slots - 1},
+		{"skip", MisfireSkip, slots},
+		{"fire all", MisfireFireAll, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := NewFakeClock(start)
+			c := NewWithLocation(time.UTC, clock)
+			runs := make(chan time.Time, 2*slots)
+			task := &testTask{
+				id:   "misfired",
+				cron: "@every 1m",
+				opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).
+					WithMisfirePolicy(5*time.Minute, test.policy),
+				run: func(ctx Context) error {
+					runs <- ctx.ExecutionDate()
+					return nil
+				},
+			}
+			if err := c.AddTask(task.Schedule(), task); err != nil {
+				t.Fatal(err)
+			}
+			c.Start()
+			defer c.Stop()
+
+			clock.BlockUntil(1)
+			clock.Advance(late)
+			// The loop waits on the next slot once it has handled the late ones.
+			clock.BlockUntil(1)
+			settle(t, c)
+
+			e := c.Entries()[0]
+			if expected := start.Add(late + time.Minute); !e.Next.Equal(expected) {
+				t.Errorf("expected the next run at %s, got %s", expected, e.Next)
+			}
+			if expected := start.Add(late); !e.Prev.Equal(expected) {
+				t.Errorf("expected the previous run at %s, got %s", expected, e.Prev)
+			}
+			if len(e.History) != slots {
+				t.Fatalf("expected a history row for each of the %d slots, got %d", slots, len(e.History))
+			}
+			for i, row := range e.History {
+				slot := start.Add(time.Duration(i+1) * time.Minute)
+				status := Success
+				if i < test.missed {
+					status = Missed
+				}
+				if row.Status != status || !row.ExecutionDate.Equal(slot) {
+					t.Errorf("row %d: expected %s for %s, got %s for %s", i, status, slot, row.Status, row.ExecutionDate)
+				}
+			}
+			if ran := len(runs); ran != slots-test.missed {
+				t.Errorf("expected %d runs, got %d", slots-test.missed, ran)
+			}
+		})
+	}
+}
+
+func TestMisfireWithinThreshold(t *testing.T) {
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	runs := make(chan time.Time, 10)
+	task := &testTask{
+		id:   "on time",
+		cron: "@hourly",
+		opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).
+			WithMisfirePolicy(5*time.Minute, MisfireSkip),
+		run: func(ctx Context) error {
+			runs <- ctx.ExecutionDate()
+			return nil
+		},
+	}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	defer c.Stop()
+
+	// Late, but by no more than the threshold.
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour + 5*time.Minute)
+	if date := nextRun(t, runs); !date.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the run for %s, got %s", start.Add(time.Hour), date)
+	}
+	clock.BlockUntil(1)
+	settle(t, c)
+	if history := c.Entries()[0].History; len(history) != 1 || history[0].Status != Success {
+		t.Errorf("expected a single successful run, got %v", history)
+	}
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// OnceSchedule activates a single time, at At.
+type OnceSchedule struct {
+	At time.Time
+}
+
+// Next returns At if it is after t, or the zero time once it has passed.
+func (s OnceSchedule) Next(t time.Time) time.Time {
+	if s.At.After(t) {
+		return s.At
+	}
+	return time.Time{}
+}
+
+// ErrNotInFuture is returned when a one-shot run is asked for at a time that
+// has already come.
+var ErrNotInFuture = errors.New("time is not in the future")
+
+// ScheduleOnce adds an entry that runs the task a single time at the given
+// time, and returns the ID of the entry. The entry is kept, with its history,
+// after it has run. The task's start and end dates still apply. It fails with
+// ErrNotInFuture unless at is after now, and with ErrEntryExists if the task
+// already has a one-shot run at that time.
+func (c *Atmo) ScheduleOnce(at time.Time, task Task) (ID, error) {
+	if now := c.now(); !at.After(now) {
+		return "", fmt.Errorf("%w: %s is not after %s", ErrNotInFuture, at.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
+	}
+	id := onceEntryID(task.TaskID(), at)
+	if err := c.schedule(id, OnceSchedule{At: at}, task); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// ScheduleAfter adds an entry that runs the task a single time once d has
+// passed, and returns the ID of the entry. d must be positive, see
+// ScheduleOnce.
+func (c *Atmo) ScheduleAfter(d time.Duration, task Task) (ID, error) {
+	return c.ScheduleOnce(c.now().Add(d), task)
+}
+
+// onceEntryID returns the ID of the one-shot entry running the task at the
+// given time. It keeps the full precision of the time, so runs of the task
+// within the same second get entries of their own.
+func onceEntryID(id ID, at time.Time) ID {
+	return ID(id.ToString() + "@" + at.UTC().Format(time.RFC3339Nano))
+}
@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleOnce(t *testing.T) {
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	runs := make(chan time.Time, 10)
+	task := &testTask{id: "once", run: func(ctx Context) error {
+		runs <- ctx.ExecutionDate()
+		return nil
+	}}
+	c.Start()
+	defer c.Stop()
+
+	// Runs of the same task within a second get entries of their own.
+	first, err := c.ScheduleOnce(start.Add(time.Minute), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.ScheduleOnce(start.Add(time.Minute+time.Millisecond), task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatalf("expected different entry IDs, got %s twice", first)
+	}
+
+	// But not two at the same time.
+	if _, err := c.ScheduleOnce(start.Add(time.Minute), task); !errors.Is(err, ErrEntryExists) {
+		t.Errorf("expected ErrEntryExists for a second run at the same time, got %v", err)
+	}
+	if len(c.Entries()) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(c.Entries()))
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if date := nextRun(t, runs); !date.Equal(start.Add(time.Minute)) {
+		t.Errorf("expected the run for %s, got %s", start.Add(time.Minute), date)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(time.Millisecond)
+	if date := nextRun(t, runs); !date.Equal(start.Add(time.Minute + time.Millisecond)) {
+		t.Errorf("expected the run for %s, got %s", start.Add(time.Minute+time.Millisecond), date)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	noRun(t, runs)
+}
+
+func TestScheduleOnceNotInFuture(t *testing.T) {
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	task := &testTask{id: "once"}
+
+	tests := []struct {
+		name     string
+		schedule func() (ID, error)
+	}{
+		{"in the past", func() (ID, error) { return c.ScheduleOnce(start.Add(-time.Second), task) }},
+		{"now", func() (ID, error) { return c.ScheduleOnce(start, task) }},
+		{"after nothing", func() (ID, error) { return c.ScheduleAfter(0, task) }},
+		{"after a negative duration", func() (ID, error) { return c.ScheduleAfter(-time.Minute, task) }},
+	}
+
+	for _, test := range tests {
+		if id, err := test.schedule(); !errors.Is(err, ErrNotInFuture) {
+			t.Errorf("%s: expected ErrNotInFuture, got %q, %v", test.name, id, err)
+		}
+	}
+	if len(c.Entries()) != 0 {
+		t.Errorf("expected no entries, got %d", len(c.Entries()))
+	}
+
+	if _, err := c.ScheduleAfter(time.Second, task); err != nil {
+		t.Errorf("expected a run a second from now to be scheduled, got %v", err)
+	}
+}
+
+func TestScheduleDuplicate(t *testing.T) {
+	task := &testTask{id: "daily", cron: "@daily"}
+	for _, running := range []bool{false, true} {
+		c := NewWithLocation(time.UTC, NewFakeClock(start))
+		if running {
+			c.Start()
+		}
+		if err := c.AddTask(task.Schedule(), task); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.AddTask(task.Schedule(), task); !errors.Is(err, ErrEntryExists) {
+			t.Errorf("running %t: expected ErrEntryExists, got %v", running, err)
+		}
+		if len(c.Entries()) != 1 {
+			t.Errorf("running %t: expected 1 entry, got %d", running, len(c.Entries()))
+		}
+		c.Stop()
+	}
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MaxStoredOutputSize is the size, in bytes of JSON, above which an output is
+// kept in memory only and not written to the Store.
+const MaxStoredOutputSize = 4 << 10
+
+// ErrOutputNotFound is returned when reading an output that was not published.
+var ErrOutputNotFound = errors.New("output not found")
+
+// Outputs holds the named values a run published through Context.SetOutput,
+// encoded as JSON.
+type Outputs map[string]json.RawMessage
+
+// Get decodes the named output into v, which must be a pointer.
+func (o Outputs) Get(name string, v interface{}) error {
+	raw, ok := o[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrOutputNotFound, name)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("output %s: %w", name, err)
+	}
+	return nil
+}
+
+// String returns the named output as a string.
+func (o Outputs) String(name string) (s string, err error) {
+	err = o.Get(name, &s)
+	return s, err
+}
+
+// Int returns the named output as an int64.
+func (o Outputs) Int(name string) (i int64, err error) {
+	err = o.Get(name, &i)
+	return i, err
+}
+
+// Float returns the named output as a float64.
+func (o Outputs) Float(name string) (f float64, err error) {
+	err = o.Get(name, &f)
+	return f, err
+}
+
+// Bool returns the named output as a bool.
+func (o Outputs) Bool(name string) (b bool, err error) {
+	err = o.Get(name, &b)
+	return b, err
+}
+
+// Time returns the named output as a time.Time.
+func (o Outputs) Time(name string) (t time.Time, err error) {
+	err = o.Get(name, &t)
+	return t, err
+}
+
+// set encodes value as JSON and stores it under name.
+func (o Outputs) set(name string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("output %s: %w", name, err)
+	}
+	o[name] = raw
+	return nil
+}
+
+// stored returns the outputs small enough to be written to the Store, as JSON
+// text, or nil if there are none.
+func (o Outputs) stored() map[string]string {
+	var small map[string]string
+	for name, raw := range o {
+		if len(raw) > MaxStoredOutputSize {
+			continue
+		}
+		if small == nil {
+			small = make(map[string]string)
+		}
+		small[name] = string(raw)
+	}
+	return small
+}
+
+// lastOutputs returns the outputs of the most recent successful run of the
+// entry, or nil if there is none.
+func (e *Entry) lastOutputs() Outputs {
+	e.RLock()
+	defer e.RUnlock()
+	for i := len(e.History) - 1; i >= 0; i-- {
+		if e.History[i].Status == Success {
+			return e.History[i].Outputs
+		}
+	}
+	return nil
+}
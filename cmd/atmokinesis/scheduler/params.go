@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrParamNotFound is returned when reading a parameter a run wasn't given.
+var ErrParamNotFound = errors.New("param not found")
+
+// Params holds the named parameters of a run, such as "region=eu" or
+// "dry_run=true". A run gets the DefaultParams of its task, overridden by those
+// it was triggered with.
+type Params map[string]string
+
+// Parameterizer is implemented by a Task that takes parameters, to declare
+// their default values. Scheduled runs use the defaults as they are.
+type Parameterizer interface {
+	DefaultParams() Params
+}
+
+// String returns the named parameter.
+func (p Params) String(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrParamNotFound, name)
+	}
+	return value, nil
+}
+
+// Int returns the named parameter as an int64.
+func (p Params) Int(name string) (int64, error) {
+	value, err := p.String(name)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", name, err)
+	}
+	return i, nil
+}
+
+// Float returns the named parameter as a float64.
+func (p Params) Float(name string) (float64, error) {
+	value, err := p.String(name)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// Bool returns the named parameter as a bool, as accepted by strconv.ParseBool.
+func (p Params) Bool(name string) (bool, error) {
+	value, err := p.String(name)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("param %s: %w", name, err)
+	}
+	return b, nil
+}
+
+// Duration returns the named parameter as a time.Duration, such as "90s".
+func (p Params) Duration(name string) (time.Duration, error) {
+	value, err := p.String(name)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", name, err)
+	}
+	return d, nil
+}
+
+// Time returns the named parameter as a time.Time, given in RFC 3339.
+func (p Params) Time(name string) (time.Time, error) {
+	value, err := p.String(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("param %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// paramsFor returns the parameters of a run of the task, its defaults
+// overridden by overrides, or nil if there are none.
+func paramsFor(task Task, overrides Params) Params {
+	var defaults Params
+	if p, ok := task.(Parameterizer); ok {
+		defaults = p.DefaultParams()
+	}
+	if len(defaults) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	params := make(Params, len(defaults)+len(overrides))
+	for name, value := range defaults {
+		params[name] = value
+	}
+	for name, value := range overrides {
+		params[name] = value
+	}
+	return params
+}
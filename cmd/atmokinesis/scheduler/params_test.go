@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// paramTask is a testTask with default parameters.
+type paramTask struct {
+	testTask
+	defaults Params
+}
+
+func (t *paramTask) DefaultParams() Params {
+	return t.defaults
+}
+
+func TestRunParams(t *testing.T) {
+	defaults := Params{"region": "eu", "dry_run": "false"}
+	tests := []struct {
+		name      string
+		defaults  Params
+		overrides Params // nil for a scheduled run
+		expected  Params
+	}{
+		{"scheduled", defaults, nil, defaults},
+		{"triggered", defaults, Params{}, defaults},
+		{"overridden", defaults, Params{"dry_run": "true", "limit": "10"},
+			Params{"region": "eu", "dry_run": "true", "limit": "10"}},
+		{"without defaults", nil, Params{"region": "us"}, Params{"region": "us"}},
+		{"none", nil, nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := NewFakeClock(start)
+			c := NewWithLocation(time.UTC, clock)
+			given := make(chan Params, 1)
+			task := &paramTask{testTask: testTask{id: "ingest", cron: "@hourly", run: func(ctx Context) error {
+				given <- ctx.Params()
+				return nil
+			}}, defaults: test.defaults}
+			if err := c.AddTask(task.Schedule(), task); err != nil {
+				t.Fatal(err)
+			}
+			c.Start()
+			defer c.Stop()
+
+			if test.overrides == nil {
+				clock.BlockUntil(1)
+				clock.Advance(time.Hour)
+			} else if err := c.TriggerWithParams(task.TaskID(), test.overrides); err != nil {
+				t.Fatal(err)
+			}
+			var params Params
+			select {
+			case params = <-given:
+			case <-time.After(wait):
+				t.Fatal("timed out waiting for a run")
+			}
+			if !reflect.DeepEqual(params, test.expected) {
+				t.Errorf("expected the run to be given %v, got %v", test.expected, params)
+			}
+			settle(t, c)
+
+			history := c.Entries()[0].History
+			if len(history) != 1 {
+				t.Fatalf("expected a single history row, got %d", len(history))
+			}
+			if row := history[0]; !reflect.DeepEqual(row.Params, test.expected) || row.Manual != (test.overrides != nil) {
+				t.Errorf("expected a row with params %v, manual %t, got %v, %t", test.expected, test.overrides != nil, row.Params, row.Manual)
+			}
+		})
+	}
+
+	// Overrides don't change the task's defaults.
+	if !reflect.DeepEqual(defaults, Params{"region": "eu", "dry_run": "false"}) {
+		t.Errorf("expected the defaults to be left alone, got %v", defaults)
+	}
+}
+
+func TestParamsStoredWithHistory(t *testing.T) {
+	row := &TaskHistory{
+		ExecutionTime: start,
+		ExecutionDate: start,
+		Status:        Success,
+		Params:        Params{"region": "eu"},
+	}
+	raw, err := bson.Marshal(historyToBSON(row))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v bson.M
+	if err := bson.Unmarshal(raw, &v); err != nil {
+		t.Fatal(err)
+	}
+	stored := historyFromBSON(start, v)
+	if !reflect.DeepEqual(stored.Params, row.Params) {
+		t.Errorf("expected params %v to be stored, got %v", row.Params, stored.Params)
+	}
+}
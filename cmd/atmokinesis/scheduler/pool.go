@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Pooler is an optional interface, see ScheduleOptions, that puts a task's runs
+// in one of the named worker pools added with Atmo.AddPool. Runs in a pool
+// count against both the pool's slots and the global limit. Runs naming a pool
+// that hasn't been added only count against the global limit, and are logged.
+//
+// Slots are taken by runs of scheduled and triggered entries. Their sub-tasks
+// run within the slot of the parent run, without taking slots of their own, so
+// a parent can't wait on slots its own sub-tasks need. The Pool and Priority
+// of a sub-task are therefore not used.
+type Pooler interface {
+	Pool() string
+}
+
+// Prioritizer is an optional interface, see ScheduleOptions, that orders runs
+// waiting for a free slot. Higher priorities run first; runs of equal priority
+// run in the order they were queued.
+type Prioritizer interface {
+	Priority() int
+}
+
+// poolOf returns the name of the pool the task runs in, or "" if it has none.
+func poolOf(task Task) string {
+	for _, v := range optionSources(task) {
+		if p, ok := v.(Pooler); ok {
+			return p.Pool()
+		}
+	}
+	return ""
+}
+
+// priorityOf returns the queue priority of the task, or zero if it has none.
+func priorityOf(task Task) int {
+	for _, v := range optionSources(task) {
+		if p, ok := v.(Prioritizer); ok {
+			return p.Priority()
+		}
+	}
+	return 0
+}
+
+// slots limits how many runs execute at once, globally and per named pool.
+// A limit of zero means unlimited.
+type slots struct {
+	mu      sync.Mutex
+	max     int
+	running int
+	pools   map[string]*pool
+	queue   []*slotWaiter
+	seq     uint64
+}
+
+type pool struct {
+	size    int
+	running int
+}
+
+type slotWaiter struct {
+	pool     string
+	priority int
+	seq      uint64
+	granted  chan struct{}
+}
+
+func newSlots() *slots {
+	return &slots{pools: map[string]*pool{}}
+}
+
+// hasPool reports whether the named pool has been added.
+func (s *slots) hasPool(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.pools[name]
+	return ok
+}
+
+// setMax changes the global limit.
+func (s *slots) setMax(max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.max = max
+	s.grant()
+}
+
+// addPool creates the named pool, or resizes it if it exists.
+func (s *slots) addPool(name string, size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.pools[name]; ok {
+		p.size = size
+	} else {
+		s.pools[name] = &pool{size: size}
+	}
+	s.grant()
+}
+
+// acquire blocks until a slot in the named pool is free, and reports whether
+// it got one. It gives up when ctx is done.
+func (s *slots) acquire(ctx context.Context, pool string, priority int) bool {
+	s.mu.Lock()
+	s.seq++
+	w := &slotWaiter{pool: pool, priority: priority, seq: s.seq, granted: make(chan struct{})}
+	s.queue = append(s.queue, w)
+	s.grant()
+	s.mu.Unlock()
+
+	select {
+	case <-w.granted:
+		return true
+	case <-ctx.Done():
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-w.granted:
+		// Granted while giving up; hand the slot back.
+		s.free(pool)
+		s.grant()
+	default:
+		for i, q := range s.queue {
+			if q == w {
+				s.queue = append(s.queue[:i], s.queue[i+1:]...)
+				break
+			}
+		}
+	}
+	return false
+}
+
+// release frees a slot taken with acquire.
+func (s *slots) release(pool string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.free(pool)
+	s.grant()
+}
+
+// free gives back a slot. It must be called with mu held.
+func (s *slots) free(pool string) {
+	s.running--
+	if p, ok := s.pools[pool]; ok {
+		p.running--
+	}
+}
+
+// grant hands free slots to queued waiters by priority. It must be called with
+// mu held.
+func (s *slots) grant() {
+	sort.SliceStable(s.queue, func(i, j int) bool {
+		if s.queue[i].priority != s.queue[j].priority {
+			return s.queue[i].priority > s.queue[j].priority
+		}
+		return s.queue[i].seq < s.queue[j].seq
+	})
+
+	waiting := s.queue[:0]
+	for _, w := range s.queue {
+		p := s.pools[w.pool]
+		if (s.max > 0 && s.running >= s.max) || (p != nil && p.size > 0 && p.running >= p.size) {
+			waiting = append(waiting, w)
+			continue
+		}
+		s.running++
+		if p != nil {
+			p.running++
+		}
+		close(w.granted)
+	}
+	for i := len(waiting); i < len(s.queue); i++ {
+		s.queue[i] = nil
+	}
+	s.queue = waiting
+}
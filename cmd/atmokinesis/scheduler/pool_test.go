@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolLimit(t *testing.T) {
+	c := NewWithLocation(time.UTC, NewFakeClock(start))
+	c.AddPool("small", 1)
+	runs := make(chan time.Time, 10)
+	release := make(chan struct{})
+	for _, id := range []ID{"first", "second"} {
+		task := &testTask{
+			id:   id,
+			cron: "@yearly",
+			opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).WithPool("small"),
+			run: func(ctx Context) error {
+				runs <- ctx.ExecutionDate()
+				<-release
+				return nil
+			},
+		}
+		if err := c.AddTask(task.Schedule(), task); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Trigger(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nextRun(t, runs)
+	noRun(t, runs)
+	close(release)
+	nextRun(t, runs)
+	settle(t, c)
+}
+
+func TestUnknownPool(t *testing.T) {
+	c := NewWithLocation(time.UTC, NewFakeClock(start))
+	var logged bytes.Buffer
+	c.ErrorLog = log.New(&logged, "", 0)
+	task := &testTask{
+		id:   "pooled",
+		cron: "@yearly",
+		opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).WithPool("missing"),
+	}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Trigger(task.TaskID()); err != nil {
+		t.Fatal(err)
+	}
+	settle(t, c)
+
+	if !strings.Contains(logged.String(), "pool missing has not been added") {
+		t.Errorf("expected the unknown pool to be logged, got %q", logged.String())
+	}
+	if e := c.entries[0]; len(e.History) != 1 || e.History[0].Status != Success {
+		t.Errorf("expected the run to go ahead, got %d runs", len(e.History))
+	}
+}
+
+func TestSubTasksDontTakeSlots(t *testing.T) {
+	c := NewWithLocation(time.UTC, NewFakeClock(start))
+	c.SetMaxConcurrency(1)
+	runs := make(chan time.Time, 10)
+	subTask := &testTask{id: "child", run: func(ctx Context) error {
+		runs <- ctx.ExecutionDate()
+		return nil
+	}}
+	task := &testTask{id: "parent", cron: "@yearly", subTasks: []Task{subTask}}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Trigger(task.TaskID()); err != nil {
+		t.Fatal(err)
+	}
+
+	nextRun(t, runs)
+	settle(t, c)
+}
@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+)
+
+// NextActivations returns the next n times the given cron string activates
+// after from. The times are worked out in loc, or in the zone of from if loc is
+// nil, unless the cron string sets its own with a CRON_TZ= prefix. Only times
+// between the start and end dates of opts are returned; opts may be nil.
+//
+// The cron string is parsed on its own, as if it belonged to a task with an
+// empty ID, so an H in it is resolved differently than for any scheduled task.
+// Use TaskActivations to preview a task as the scheduler will run it.
+func NextActivations(cron Cron, n int, from time.Time, loc *time.Location, opts ScheduleOptions) ([]time.Time, error) {
+	schedule, err := ParseStandard(cron)
+	if err != nil {
+		return nil, err
+	}
+	if loc != nil {
+		from = from.In(loc)
+	}
+	if opts == nil {
+		opts = StartImmediately{}
+	}
+	return activations(n, from, func(t time.Time) time.Time {
+		return nextActivation(schedule, opts, t)
+	}), nil
+}
+
+// TaskActivations returns the next n times the task would run after from, with
+// the schedule InitScheduler builds for it: all of its cron strings and
+// exclusions, H resolved from its TaskID, and its DST policy, jitter and
+// calendars. The times are worked out in loc, or in the zone of from if loc is
+// nil.
+func TaskActivations(task Task, n int, from time.Time, loc *time.Location) ([]time.Time, error) {
+	schedule, err := taskSchedule(task)
+	if err != nil {
+		return nil, err
+	}
+	if loc != nil {
+		from = from.In(loc)
+	}
+	opts := task.ScheduleOptions()
+	return activations(n, from, func(t time.Time) time.Time {
+		return nextActivation(schedule, opts, t)
+	}), nil
+}
+
+// activations returns up to n times by calling next repeatedly, starting at
+// from. It stops early once next returns the zero time.
+func activations(n int, from time.Time, next func(time.Time) time.Time) []time.Time {
+	times := make([]time.Time, 0, n)
+	for t := from; len(times) < n; {
+		if t = next(t); t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// PreviewTask returns the next n times the scheduled task with the given ID
+// will run, as the scheduler works them out.
+func PreviewTask(id ID, n int) ([]time.Time, error) {
+	for _, e := range sch.atmo.entrySnapshot() {
+		if e.ID == id {
+			return activations(n, sch.atmo.now(), e.nextAfter), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+}
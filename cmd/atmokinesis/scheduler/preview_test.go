@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// multiTask is a testTask that runs on several cron strings, minus some.
+type multiTask struct {
+	testTask
+	schedules, exclusions []Cron
+}
+
+func (t *multiTask) Schedules() []Cron {
+	return t.schedules
+}
+
+func (t *multiTask) Exclusions() []Cron {
+	return t.exclusions
+}
+
+func TestTaskActivationsMatchScheduler(t *testing.T) {
+	runs := make(chan time.Time, 10)
+	task := &multiTask{
+		testTask: testTask{
+			id:   "report",
+			cron: "H H(0-5) * * *",
+			opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, true, false).WithJitter(10 * time.Minute),
+			run: func(ctx Context) error {
+				runs <- ctx.ExecutionDate()
+				return nil
+			},
+		},
+		schedules:  []Cron{"H 12 * * MON-FRI"},
+		exclusions: []Cron{"* * * * SAT"},
+	}
+
+	preview, err := TaskActivations(task, 8, start, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview) != 8 {
+		t.Fatalf("expected 8 activations, got %d", len(preview))
+	}
+
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	schedule, err := scheduleFor(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Schedule(schedule, task)
+	c.Start()
+	defer c.Stop()
+
+	for _, expected := range preview {
+		if expected.Weekday() == time.Saturday {
+			t.Errorf("preview includes %s, which is excluded", expected)
+		}
+		clock.BlockUntil(1)
+		clock.Set(expected)
+		if date := nextRun(t, runs); !date.Equal(expected) {
+			t.Fatalf("preview has %s, but the scheduler ran for %s", expected, date)
+		}
+	}
+}
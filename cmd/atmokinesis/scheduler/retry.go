@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"math"
+	"time"
+)
+
+// RetryPolicy describes how a failed run is retried before it is given up on.
+// Every attempt is recorded in the entry's history.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialDelay is the wait before the first retry.
+	InitialDelay time.Duration
+
+	// Multiplier grows the delay after every retry. Values below 1 keep the
+	// delay at InitialDelay.
+	Multiplier float64
+
+	// MaxDelay caps the delay between two attempts. Zero means no cap.
+	MaxDelay time.Duration
+
+	// Retryable reports whether a run that failed with err should be retried.
+	// A nil Retryable retries every error.
+	Retryable func(err error) bool
+}
+
+// Retrier is an optional interface, see ScheduleOptions, that has failed runs
+// retried.
+type Retrier interface {
+	RetryPolicy() *RetryPolicy
+}
+
+// retryPolicyOf returns the retry policy of the task, or nil if it has none.
+func retryPolicyOf(task Task) *RetryPolicy {
+	for _, v := range optionSources(task) {
+		if r, ok := v.(Retrier); ok {
+			return r.RetryPolicy()
+		}
+	}
+	return nil
+}
+
+// retries reports whether another attempt should follow the given attempt,
+// which failed with err.
+func (p *RetryPolicy) retries(attempt int, err error) bool {
+	if p == nil || attempt >= p.MaxAttempts {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// delay returns the wait between the given attempt and the next one.
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	multiplier := math.Max(p.Multiplier, 1)
+	delay := float64(p.InitialDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		return p.MaxDelay
+	}
+	return time.Duration(delay)
+}
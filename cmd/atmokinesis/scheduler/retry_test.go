@@ -0,0 +1,154 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		expected []time.Duration
+	}{
+		{"exponential", RetryPolicy{InitialDelay: time.Second, Multiplier: 2},
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second}},
+		{"capped", RetryPolicy{InitialDelay: time.Second, Multiplier: 2, MaxDelay: 5 * time.Second},
+			[]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{"fractional", RetryPolicy{InitialDelay: time.Second, Multiplier: 1.5},
+			[]time.Duration{time.Second, 1500 * time.Millisecond, 2250 * time.Millisecond}},
+		{"constant", RetryPolicy{InitialDelay: time.Minute},
+			[]time.Duration{time.Minute, time.Minute, time.Minute}},
+		{"multiplier below 1", RetryPolicy{InitialDelay: time.Minute, Multiplier: 0.5},
+			[]time.Duration{time.Minute, time.Minute, time.Minute}},
+	}
+
+	for _, test := range tests {
+		for i, expected := range test.expected {
+			if delay := test.policy.delay(i + 1); delay != expected {
+				t.Errorf("%s: expected a delay of %s after attempt %d, got %s", test.name, expected, i+1, delay)
+			}
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	policy := &RetryPolicy{
+		MaxAttempts: 3,
+		Retryable:   func(err error) bool { return !errors.Is(err, errPermanent) },
+	}
+	tests := []struct {
+		policy   *RetryPolicy
+		attempt  int
+		err      error
+		expected bool
+	}{
+		{nil, 1, errBoom, false},
+		{&RetryPolicy{MaxAttempts: 1}, 1, errBoom, false},
+		{&RetryPolicy{MaxAttempts: 2}, 1, errBoom, true},
+		{policy, 1, errBoom, true},
+		{policy, 2, errBoom, true},
+		{policy, 3, errBoom, false},
+		{policy, 1, errPermanent, false},
+	}
+
+	for _, test := range tests {
+		if retries := test.policy.retries(test.attempt, test.err); retries != test.expected {
+			t.Errorf("%+v, attempt %d, %s: expected %t, got %t", test.policy, test.attempt, test.err, test.expected, retries)
+		}
+	}
+}
+
+func TestRetryRun(t *testing.T) {
+	backoff := RetryPolicy{MaxAttempts: 3, InitialDelay: time.Minute, Multiplier: 2}
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		fails  int
+		// The attempts expected, by how long after the first one they start.
+		offsets  []time.Duration
+		statuses []EntryStatus
+	}{
+		{"succeeds first", backoff, 0,
+			[]time.Duration{0},
+			[]EntryStatus{Success}},
+		{"succeeds on retry", backoff, 2,
+			[]time.Duration{0, time.Minute, 3 * time.Minute},
+			[]EntryStatus{Failing, Failing, Success}},
+		{"runs out of attempts", backoff, 5,
+			[]time.Duration{0, time.Minute, 3 * time.Minute},
+			[]EntryStatus{Failing, Failing, Failing}},
+		{"not retryable", RetryPolicy{MaxAttempts: 3, InitialDelay: time.Minute, Retryable: func(error) bool { return false }}, 5,
+			[]time.Duration{0},
+			[]EntryStatus{Failing}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := NewFakeClock(start)
+			c := NewWithLocation(time.UTC, clock)
+			runs := make(chan time.Time, 10)
+			attempts := 0
+			task := &testTask{
+				id:   "retried",
+				cron: "@yearly",
+				opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false).WithRetryPolicy(test.policy),
+				run: func(ctx Context) error {
+					runs <- ctx.ExecutionDate()
+					attempts++
+					if attempts <= test.fails {
+						return errBoom
+					}
+					return nil
+				},
+			}
+			if err := c.AddTask(task.Schedule(), task); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Trigger(task.TaskID()); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, offset := range test.offsets {
+				if i > 0 {
+					// The retry waits on the clock; it must not start early.
+					clock.BlockUntil(1)
+					clock.Set(start.Add(offset - time.Second))
+					noRun(t, runs)
+					clock.Set(start.Add(offset))
+				}
+				if date := nextRun(t, runs); !date.Equal(start) {
+					t.Fatalf("expected every attempt to run for %s, got %s", start, date)
+				}
+			}
+			settle(t, c)
+
+			e := c.entries[0]
+			if len(e.History) != len(test.statuses) {
+				t.Fatalf("expected %d history rows, got %d", len(test.statuses), len(e.History))
+			}
+			for i, row := range e.History {
+				if row.Attempt != i+1 || row.Status != test.statuses[i] || !row.Manual {
+					t.Errorf("row %d: expected attempt %d %s, got attempt %d %s", i, i+1, test.statuses[i], row.Attempt, row.Status)
+				}
+				if executed := start.Add(test.offsets[i]); !row.ExecutionTime.Equal(executed) {
+					t.Errorf("row %d: expected it to run at %s, got %s", i, executed, row.ExecutionTime)
+				}
+				if !row.ExecutionDate.Equal(start) {
+					t.Errorf("row %d: expected it to stand for %s, got %s", i, start, row.ExecutionDate)
+				}
+			}
+			failures := 0
+			for _, status := range test.statuses {
+				if status == Failing {
+					failures++
+				}
+			}
+			if len(e.Errors) != failures {
+				t.Errorf("expected %d errors, got %d", failures, len(e.Errors))
+			}
+		})
+	}
+}
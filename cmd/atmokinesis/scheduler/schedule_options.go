@@ -5,18 +5,51 @@ import (
 )
 
 // ScheduleOptions
+//
+// Further options are set through optional interfaces, such as Retrier,
+// Timeouter or Pooler, which the Task or its ScheduleOptions may implement.
+// The Task is checked first, so a task type can override its options; the
+// first of the two to implement the interface is used, and its default
+// applies if neither does.
 type ScheduleOptions interface {
 	StartDate() time.Time
 	EndDate() time.Time
 	StopOnFailure() bool // Should we stop on a Failure
 	AllowOverlap() bool
-	Rescue() bool // Catch up on previous failed tasks
+	Rescue() bool // Catch up on slots missed while not running, see Atmo.Backfill
+}
+
+// optionSources returns where the optional interfaces of the task are looked
+// up, in order of precedence; see ScheduleOptions.
+func optionSources(task Task) []interface{} {
+	return []interface{}{task, task.ScheduleOptions()}
 }
 
 func NoEndDate() time.Time {
 	return time.Date(2999, 1, 1, 0, 0, 0, 0, time.Local)
 }
 
+// nextActivation returns the first activation of schedule after t that lies
+// between the start and end dates of opts. A zero end date means the schedule
+// never ends. It returns the zero time once the end date has passed.
+func nextActivation(schedule Schedule, opts ScheduleOptions, t time.Time) time.Time {
+	var next time.Time
+	start := opts.StartDate()
+	switch {
+	case !t.Before(start):
+		next = schedule.Next(t)
+	case schedule.Next(start.Add(-time.Second)).Equal(start):
+		// The start date is an activation time itself.
+		next = start
+	default:
+		next = schedule.Next(start)
+	}
+	if end := opts.EndDate(); !end.IsZero() && next.After(end) {
+		return time.Time{}
+	}
+	return next
+}
+
 // NewScheduleOptions
 func NewScheduleOptions(startDate, endDate time.Time, stopOnFailure, allowOverlap, rescue bool) *DefaultScheduleOptions {
 	return &DefaultScheduleOptions{startDate: startDate, endDate: endDate, stopOnFailure: stopOnFailure, allowOverlap: allowOverlap, rescue: rescue}
@@ -29,6 +62,85 @@ type DefaultScheduleOptions struct {
 	stopOnFailure bool
 	allowOverlap  bool
 	rescue        bool
+	retryPolicy   *RetryPolicy
+	timeout       time.Duration
+	pool          string
+	priority      int
+	dstPolicy     DSTPolicy
+	calendars     []string
+	calendarTreat CalendarPolicy
+	jitter        time.Duration
+	misfireAfter  time.Duration
+	misfire       MisfirePolicy
+	mapLimit      int
+	subTaskPolicy SubTaskPolicy
+}
+
+// WithRetryPolicy retries failed runs according to policy.
+func (d *DefaultScheduleOptions) WithRetryPolicy(policy RetryPolicy) *DefaultScheduleOptions {
+	d.retryPolicy = &policy
+	return d
+}
+
+// WithTimeout cancels runs that take longer than timeout.
+func (d *DefaultScheduleOptions) WithTimeout(timeout time.Duration) *DefaultScheduleOptions {
+	d.timeout = timeout
+	return d
+}
+
+// WithPool runs the task in the named worker pool, see Atmo.AddPool.
+func (d *DefaultScheduleOptions) WithPool(pool string) *DefaultScheduleOptions {
+	d.pool = pool
+	return d
+}
+
+// WithPriority orders the task's runs among those waiting for a free slot.
+func (d *DefaultScheduleOptions) WithPriority(priority int) *DefaultScheduleOptions {
+	d.priority = priority
+	return d
+}
+
+// WithDSTPolicy chooses how the task's cron schedule treats daylight saving
+// time transitions.
+func (d *DefaultScheduleOptions) WithDSTPolicy(policy DSTPolicy) *DefaultScheduleOptions {
+	d.dstPolicy = policy
+	return d
+}
+
+// WithCalendars keeps the task's runs off the days of the named calendars,
+// skipping or shifting them according to policy. See RegisterCalendar.
+func (d *DefaultScheduleOptions) WithCalendars(policy CalendarPolicy, names ...string) *DefaultScheduleOptions {
+	d.calendars = names
+	d.calendarTreat = policy
+	return d
+}
+
+// WithJitter delays each run of the task by up to jitter, see Jitterer.
+func (d *DefaultScheduleOptions) WithJitter(jitter time.Duration) *DefaultScheduleOptions {
+	d.jitter = jitter
+	return d
+}
+
+// WithMisfirePolicy handles runs that start more than threshold late
+// according to policy, see Misfirer.
+func (d *DefaultScheduleOptions) WithMisfirePolicy(threshold time.Duration, policy MisfirePolicy) *DefaultScheduleOptions {
+	d.misfireAfter = threshold
+	d.misfire = policy
+	return d
+}
+
+// WithMapConcurrency caps how many mapped sub-task instances of the task run at
+// once, see MapLimiter.
+func (d *DefaultScheduleOptions) WithMapConcurrency(limit int) *DefaultScheduleOptions {
+	d.mapLimit = limit
+	return d
+}
+
+// WithSubTaskPolicy decides the outcome of the task's runs from those of its
+// sub-tasks, see SubTaskPolicer.
+func (d *DefaultScheduleOptions) WithSubTaskPolicy(policy SubTaskPolicy) *DefaultScheduleOptions {
+	d.subTaskPolicy = policy
+	return d
 }
 
 func (d DefaultScheduleOptions) StartDate() time.Time {
@@ -51,6 +163,54 @@ func (d DefaultScheduleOptions) Rescue() bool {
 	return d.rescue
 }
 
+func (d DefaultScheduleOptions) RetryPolicy() *RetryPolicy {
+	return d.retryPolicy
+}
+
+func (d DefaultScheduleOptions) Timeout() time.Duration {
+	return d.timeout
+}
+
+func (d DefaultScheduleOptions) Pool() string {
+	return d.pool
+}
+
+func (d DefaultScheduleOptions) Priority() int {
+	return d.priority
+}
+
+func (d DefaultScheduleOptions) DSTPolicy() DSTPolicy {
+	return d.dstPolicy
+}
+
+func (d DefaultScheduleOptions) Calendars() []string {
+	return d.calendars
+}
+
+func (d DefaultScheduleOptions) CalendarPolicy() CalendarPolicy {
+	return d.calendarTreat
+}
+
+func (d DefaultScheduleOptions) Jitter() time.Duration {
+	return d.jitter
+}
+
+func (d DefaultScheduleOptions) MisfireThreshold() time.Duration {
+	return d.misfireAfter
+}
+
+func (d DefaultScheduleOptions) MisfirePolicy() MisfirePolicy {
+	return d.misfire
+}
+
+func (d DefaultScheduleOptions) MapConcurrency() int {
+	return d.mapLimit
+}
+
+func (d DefaultScheduleOptions) SubTaskPolicy() SubTaskPolicy {
+	return d.subTaskPolicy
+}
+
 func NewStartImmediately(stopOnFailure, allowOverlap, rescue bool) ScheduleOptions {
 	return &StartImmediately{
 		stopOnFailure: stopOnFailure,
@@ -66,8 +226,10 @@ type StartImmediately struct {
 	allowOverlap  bool
 }
 
+// StartDate returns the zero time, so the schedule is active from the moment
+// the task is registered.
 func (s StartImmediately) StartDate() time.Time {
-	return time.Now()
+	return time.Time{}
 }
 
 func (s StartImmediately) EndDate() time.Time {
@@ -83,5 +245,5 @@ func (s StartImmediately) AllowOverlap() bool {
 }
 
 func (s StartImmediately) Rescue() bool {
-	return s.Rescue()
+	return s.rescue
 }
@@ -1,6 +1,9 @@
 package scheduler
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"runtime"
@@ -15,13 +18,42 @@ import (
 type Atmo struct {
 	entries  []*Entry
 	stop     chan struct{}
-	add      chan *Entry
+	add      chan entryRequest
+	trigger  chan entryRequest
+	pause    chan entryRequest
+	resume   chan entryRequest
+	remove   chan entryRequest
 	snapshot chan []*Entry
+	backfill chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
 	running  bool
+
+	// inflight counts the dispatched runs that have not returned yet, and
+	// interrupted is closed, once, when Shutdown stops waiting for them.
+	inflight      sync.WaitGroup
+	interrupted   chan struct{}
+	interruptOnce *sync.Once
+
+	// slots limits how many runs execute at once.
+	slots *slots
+
+	// children holds the entries of sub-tasks by ID, see childEntry.
+	children   map[ID]*Entry
+	childrenMu sync.Mutex
+
 	ErrorLog *log.Logger
 	location *time.Location
+	clock    Clock
+
+	// RescueLimit caps how many missed slots Backfill runs per entry. It
+	// doesn't apply to misfires, see MisfirePolicy.
+	RescueLimit int
 }
 
+// DefaultRescueLimit is the RescueLimit of a new Atmo.
+const DefaultRescueLimit = 10
+
 // The Schedule describes a job's duty cycle.
 type Schedule interface {
 	// Return the next activation time, later than the given time.
@@ -31,8 +63,58 @@ type Schedule interface {
 
 type TaskHistory struct {
 	ExecutionTime time.Time   `json:"execution_time"`
+	ExecutionDate time.Time   `json:"execution_date"` // the schedule slot the run stands for
 	Status        EntryStatus `json:"status,omitempty"`
 	Logs          string      `json:"logs,omitempty"`
+	Manual        bool        `json:"manual,omitempty"`  // started by Trigger rather than the schedule
+	Attempt       int         `json:"attempt,omitempty"` // 1 for the first try, counting up on retries
+	Outputs       Outputs     `json:"outputs,omitempty"` // published by the run through Context.SetOutput
+	Params        Params      `json:"params,omitempty"`  // given to the run, defaults included
+
+	// Children holds the rows of the sub-task instances the run spawned with
+	// Context.MapSubTask, each naming its instance in SubTask.
+	Children []*TaskHistory `json:"children,omitempty"`
+	SubTask  ID             `json:"sub_task,omitempty"`
+}
+
+// errInterrupted is recorded for runs that did not return within the grace
+// period of Shutdown.
+var errInterrupted = errors.New("run interrupted by scheduler shutdown")
+
+// ErrTaskNotFound is returned when an operation names a task ID that has no entry.
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrEntryExists is returned when an entry is added with the ID of one the
+// scheduler already has.
+var ErrEntryExists = errors.New("entry already exists")
+
+// runRequest describes a single run of an entry.
+type runRequest struct {
+	executionDate time.Time // the schedule slot the run stands for
+	manual        bool      // started by Trigger rather than the schedule
+	params        Params    // overriding the task's DefaultParams
+
+	// The entry's next and previous activations as of when the run was
+	// dispatched, or those of the parent run for a sub-task.
+	nextRunDate, prevRunDate time.Time
+
+	// For sub-task runs, the outputs of the parent run and the instance a
+	// mapped sub-task run stands for.
+	parentOutputs Outputs
+	item          *mappedSubTask
+
+	// transient is set for the sub-tasks of a mapped instance, and theirs,
+	// whose entries are made for the run only, see runSubTasks.
+	transient bool
+}
+
+// entryRequest is sent to the run loop to act on the entry with the given ID.
+// The outcome is reported back on err.
+type entryRequest struct {
+	id     ID
+	params Params // for Trigger
+	entry  *Entry // for adding
+	err    chan error
 }
 
 // byTime is a wrapper for sorting the entry array by time
@@ -56,47 +138,291 @@ func (s byTime) Less(i, j int) bool {
 
 // NewCron returns a new Atmo job runner, in the Local time zone.
 func NewCron() *Atmo {
-	return NewWithLocation(time.Now().Location())
+	return NewWithLocation(time.Now().Location(), RealClock())
 }
 
-// NewWithLocation returns a new Atmo job runner.
-func NewWithLocation(location *time.Location) *Atmo {
+// NewWithLocation returns a new Atmo job runner that tells the time with clock.
+// A nil clock means the wall clock.
+func NewWithLocation(location *time.Location, clock Clock) *Atmo {
+	if clock == nil {
+		clock = RealClock()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Atmo{
 		entries:  []*Entry{},
-		add:      make(chan *Entry),
+		add:      make(chan entryRequest),
+		trigger:  make(chan entryRequest),
+		pause:    make(chan entryRequest),
+		resume:   make(chan entryRequest),
+		remove:   make(chan entryRequest),
+		backfill: make(chan struct{}),
 		stop:     make(chan struct{}),
 		snapshot: make(chan []*Entry),
+		ctx:      ctx,
+		cancel:   cancel,
 		running:  false,
 		ErrorLog: nil,
 		location: location,
+		clock:    clock,
+
+		interrupted:   make(chan struct{}),
+		interruptOnce: new(sync.Once),
+		slots:         newSlots(),
+		children:      make(map[ID]*Entry),
+		RescueLimit:   DefaultRescueLimit,
 	}
 }
 
 // AddJob adds a Task to the Atmo to be run on the given schedule.
 func (c *Atmo) AddTask(cron Cron, task Task) error {
-	schedule, err := Parse(cron)
+	schedule, err := defaultParser.ForTask(task.TaskID()).Parse(cron)
 	if err != nil {
 		return err
 	}
-	c.Schedule(schedule, task)
-	return nil
+	return c.Schedule(schedule, task)
+}
+
+// Schedule adds a Task to the Atmo to be run on the given schedule. It fails
+// with ErrEntryExists if a task with the same ID was added before.
+func (c *Atmo) Schedule(schedule Schedule, task Task) error {
+	names, _ := calendarsOf(task)
+	for _, name := range names {
+		if lookupCalendar(name) == nil {
+			c.logf("[%s] calendar %s is not registered yet", task.TaskID().ToString(), name)
+		}
+	}
+	return c.schedule(task.TaskID(), withTaskOptions(schedule, task), task)
+}
+
+// withTaskOptions returns schedule with the DST policy, jitter and calendars of
+// the task applied.
+func withTaskOptions(schedule Schedule, task Task) Schedule {
+	return withCalendars(withJitter(withDSTPolicy(schedule, dstPolicyOf(task)), task), task)
 }
 
-// Schedule adds a Task to the Atmo to be run on the given schedule.
-func (c *Atmo) Schedule(schedule Schedule, task Task) {
+// schedule adds an entry with the given ID that runs the task on schedule.
+func (c *Atmo) schedule(id ID, schedule Schedule, task Task) error {
 	entry := &Entry{
+		ID:       id,
 		Schedule: schedule,
 		Status:   PendingRun,
 		Task:     task,
 		Errors:   make(map[time.Time]error),
 		RWMutex:  new(sync.RWMutex),
 	}
+	if err := c.addEntry(entry); err != nil {
+		return err
+	}
+	if _, ok := schedule.(OnceSchedule); !ok {
+		c.addChildren(entry)
+	}
+	return nil
+}
+
+// addEntry adds the entry to the scheduler, unless it has an entry with the
+// same ID already.
+func (c *Atmo) addEntry(entry *Entry) error {
+	if !c.running {
+		return c.appendEntry(entry)
+	}
+	req := entryRequest{entry: entry, err: make(chan error, 1)}
+	c.add <- req
+	return <-req.err
+}
+
+func (c *Atmo) appendEntry(entry *Entry) error {
+	if c.entryByID(entry.ID) != nil {
+		return fmt.Errorf("%w: %s", ErrEntryExists, entry.ID)
+	}
+	c.entries = append(c.entries, entry)
+	return nil
+}
+
+// SetMaxConcurrency limits how many runs execute at once across all tasks.
+// Runs over the limit are queued by priority. Zero means unlimited. Sub-task
+// runs don't count, see Pooler.
+func (c *Atmo) SetMaxConcurrency(max int) {
+	c.slots.setMax(max)
+}
+
+// AddPool creates a named worker pool that lets at most size runs of the tasks
+// in it execute at once, or resizes the pool if it exists. Zero means
+// unlimited. Tasks join a pool by implementing Pooler.
+func (c *Atmo) AddPool(name string, size int) {
+	c.slots.addPool(name, size)
+}
+
+// Trigger runs the task with the given ID now, outside of its schedule. The run
+// waits for any in-flight run to finish unless the task allows overlapping runs.
+func (c *Atmo) Trigger(id ID) error {
+	return c.TriggerWithParams(id, nil)
+}
+
+// TriggerWithParams is like Trigger, but runs the task with params overriding
+// its DefaultParams.
+func (c *Atmo) TriggerWithParams(id ID, params Params) error {
 	if !c.running {
-		c.entries = append(c.entries, entry)
+		return c.triggerEntry(id, params)
+	}
+	req := entryRequest{id: id, params: params, err: make(chan error, 1)}
+	c.trigger <- req
+	return <-req.err
+}
+
+func (c *Atmo) triggerEntry(id ID, params Params) error {
+	e := c.entryByID(id)
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	c.dispatch(e, runRequest{executionDate: c.now(), manual: true, params: params})
+	return nil
+}
+
+// Pause stops the task with the given ID from being scheduled until it is
+// resumed. A run that is already in progress is left to finish.
+func (c *Atmo) Pause(id ID) error {
+	return c.request(c.pause, c.pauseEntry, id)
+}
+
+// Resume schedules a paused task again, starting from its next activation
+// time after now.
+func (c *Atmo) Resume(id ID) error {
+	return c.request(c.resume, c.resumeEntry, id)
+}
+
+// Remove takes the task with the given ID out of the scheduler. Its stored
+// history is kept.
+func (c *Atmo) Remove(id ID) error {
+	return c.request(c.remove, c.removeEntry, id)
+}
+
+// request hands id to the run loop through ch, or applies fn directly when the
+// scheduler is not running.
+func (c *Atmo) request(ch chan entryRequest, fn func(id ID) error, id ID) error {
+	if !c.running {
+		return fn(id)
+	}
+	req := entryRequest{id: id, err: make(chan error, 1)}
+	ch <- req
+	return <-req.err
+}
+
+func (c *Atmo) pauseEntry(id ID) error {
+	e := c.entryByID(id)
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	e.Lock()
+	e.Paused = true
+	e.Status = Stopped
+	e.Unlock()
+	e.Next = time.Time{}
+	return nil
+}
+
+func (c *Atmo) resumeEntry(id ID) error {
+	e := c.entryByID(id)
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+	}
+	e.Lock()
+	e.Paused = false
+	e.StopReason = ""
+	e.Status = PendingRun
+	e.Unlock()
+	if c.running {
+		e.Next = e.nextAfter(c.now())
+	}
+	return nil
+}
+
+func (c *Atmo) removeEntry(id ID) error {
+	for i, e := range c.entries {
+		if e.ID == id {
+			c.entries = append(c.entries[:i], c.entries[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+}
+
+// Backfill runs the slots each rescue-enabled entry missed between its last
+// run and now, oldest first. Only the most recent RescueLimit slots are run.
+// It is meant to be called once the entries' previous runs have been loaded
+// from the Store.
+func (c *Atmo) Backfill() {
+	if !c.running {
+		c.backfillEntries(c.now())
+		return
+	}
+	c.backfill <- struct{}{}
+}
+
+func (c *Atmo) backfillEntries(now time.Time) {
+	for _, e := range c.entries {
+		if e.isPaused() || !e.Task.ScheduleOptions().Rescue() {
+			continue
+		}
+		last := e.Prev
+		e.RLock()
+		if last.IsZero() && len(e.History) > 0 {
+			last = e.History[len(e.History)-1].ExecutionTime
+		}
+		e.RUnlock()
+		if last.IsZero() {
+			continue
+		}
+
+		missed := c.missedSlots(e, e.nextAfter(last), now)
+		if len(missed) == 0 {
+			continue
+		}
+		if len(missed) > c.RescueLimit {
+			c.logf("[%s] skipping %d missed runs beyond the rescue limit of %d",
+				e.Task.TaskID().ToString(), len(missed)-c.RescueLimit, c.RescueLimit)
+			missed = missed[len(missed)-c.RescueLimit:]
+		}
+		c.dispatch(e, missed...)
+		e.Prev = missed[len(missed)-1].executionDate
+	}
+}
+
+// missedSlots returns a run request for every activation of the entry from
+// first until now, oldest first.
+func (c *Atmo) missedSlots(e *Entry, first, now time.Time) []runRequest {
+	var missed []runRequest
+	for slot := first; !slot.IsZero() && !slot.After(now); slot = e.nextAfter(slot) {
+		missed = append(missed, runRequest{executionDate: slot})
+	}
+	return missed
+}
+
+// fire runs the entry's due slot and moves the entry on to its next slot after
+// now. If the due slot is later than the task's misfire threshold, it handles
+// the slots missed up to now by the misfire policy.
+func (c *Atmo) fire(e *Entry, now time.Time) {
+	due := e.Next
+	e.Next = e.nextAfter(now)
+	threshold, policy := misfireOf(e.Task)
+	if threshold <= 0 || now.Sub(due) <= threshold {
+		c.dispatch(e, runRequest{executionDate: due})
+		e.Prev = due
 		return
 	}
 
-	c.add <- entry
+	missed := c.missedSlots(e, due, now)
+	last := missed[len(missed)-1]
+	c.logf("[%s] %d runs started more than %s late", e.Task.TaskID().ToString(), len(missed), threshold)
+	switch policy {
+	case MisfireSkip:
+		c.recordHistory(e, Missed, missed...)
+	case MisfireFireAll:
+		c.dispatch(e, missed...)
+	default:
+		c.recordHistory(e, Missed, missed[:len(missed)-1]...)
+		c.dispatch(e, last)
+	}
+	e.Prev = last.executionDate
 }
 
 // Entries returns a snapshot of the atmo entries.
@@ -120,6 +446,7 @@ func (c *Atmo) Start() {
 		return
 	}
 	c.running = true
+	c.renewContext()
 	go c.run()
 }
 
@@ -129,83 +456,280 @@ func (c *Atmo) Run() {
 		return
 	}
 	c.running = true
+	c.renewContext()
 	c.run()
 }
 
-func (c *Atmo) runWithRecovery(ctx Context, e *Entry, notify chan bool, parentStream chan interface{}) {
+// renewContext replaces the context runs execute in if a previous Stop has
+// cancelled it.
+func (c *Atmo) renewContext() {
+	if c.ctx.Err() != nil {
+		c.ctx, c.cancel = context.WithCancel(context.Background())
+		c.interrupted = make(chan struct{})
+		c.interruptOnce = new(sync.Once)
+	}
+}
+
+// runWithRecovery runs the task of the entry, retrying it according to its
+// RetryPolicy, and then its sub-tasks. It returns the history row of the last
+// attempt and the outcome of the run, which takes the sub-tasks into account.
+func (c *Atmo) runWithRecovery(e *Entry, parentStream chan interface{}, req runRequest) (*TaskHistory, error) {
 	var stream chan interface{}
-	var buffer, logWriter = NewBaseWriteSyncer()
-	executionTime := time.Now()
+
+	log.Printf("[%s] started", e.Task.TaskID().ToString())
+	defer log.Printf("[%s] finished", e.Task.TaskID().ToString())
+
+	req.params = paramsFor(e.Task, req.params)
+	policy := retryPolicyOf(e.Task)
+	previous := e.lastOutputs()
+	next, prev := req.nextRunDate, req.prevRunDate
+	var (
+		err     error
+		row     *TaskHistory
+		outputs Outputs
+		mapped  []mappedSubTask
+	)
+	for attempt := 1; ; attempt++ {
+		if !e.Task.ScheduleOptions().AllowOverlap() && !c.waitForTimedOut(e) {
+			return row, errInterrupted
+		}
+		buffer, logWriter := NewBaseWriteSyncer()
+		runCtx, cancel := runContext(c.ctx, e.Task)
+		var ctx Context
+		ctx, _, stream = NewBaseContext(runCtx, req.executionDate, c.clock.Now(), next, prev, parentStream, logWriter)
+		bc := ctx.(*BaseContext)
+		bc.previousOutputs, bc.parentOutputs, bc.item = previous, req.parentOutputs, req.item
+		bc.params = req.params
+
+		row, err = c.runAttempt(ctx, e, req, attempt, buffer, logWriter)
+		outputs = ctx.Outputs()
+		mapped = mappedSubTasksOf(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+		if c.ctx.Err() != nil || !policy.retries(attempt, err) {
+			c.stopOnFailure(e, row, req.executionDate, err)
+			return row, err
+		}
+		delay := policy.delay(attempt)
+		c.logf("[%s] attempt %d failed, retrying in %s: %s", e.Task.TaskID().ToString(), attempt, delay, err)
+		select {
+		case <-c.clock.After(delay):
+		case <-c.ctx.Done():
+			return row, err
+		}
+	}
+
+	if _, subTasks := e.Task.SubTasks(); (len(subTasks) == 0 && len(mapped) == 0) || c.ctx.Err() != nil {
+		return row, nil
+	}
+	results := c.runSubTasks(e, row, runRequest{
+		executionDate: req.executionDate,
+		manual:        req.manual,
+		params:        req.params,
+		parentOutputs: outputs,
+		nextRunDate:   next,
+		prevRunDate:   prev,
+		transient:     req.transient || req.item != nil,
+	}, stream, mapped)
+	if err = c.fanIn(e, row, results); err != nil {
+		c.stopOnFailure(e, row, req.executionDate, err)
+	}
+	return row, err
+}
+
+// runAttempt runs the task once and records the attempt in the entry's
+// history. A panicking task is recorded as a failed attempt. Once ctx is done
+// the attempt is given up on, whether or not the task has returned; see
+// leaveRunning.
+func (c *Atmo) runAttempt(ctx Context, e *Entry, req runRequest, attempt int, buffer *bytes.Buffer, logWriter WriteSyncer) (row *TaskHistory, err error) {
+	executionTime := c.clock.Now()
 
 	defer func() {
-		if r := recover(); r != nil {
-			const size = 64 << 10
-			buf := make([]byte, size)
-			buf = buf[:runtime.Stack(buf, false)]
-			e.History = append(e.History, &TaskHistory{
-				ExecutionTime: executionTime,
-				Status:        Failing,
-				Logs:          string(buffer.Bytes()),
-			})
-			e.Errors[e.Prev] = fmt.Errorf("task is panicing: %s", string(buf))
+		status, settled := Success, PendingRun
+		switch {
+		case err == errInterrupted, err != nil && c.ctx.Err() != nil && errors.Is(err, context.Canceled):
+			status, settled = Interrupted, Interrupted
+		case err != nil && ctx.Err() == context.DeadlineExceeded:
+			err = fmt.Errorf("task timed out after %s: %w", timeoutOf(e.Task), context.DeadlineExceeded)
+			status, settled = TimedOut, TimedOut
+		case err != nil:
+			status, settled = Failing, Failing
+		}
+		e.settleStatus(settled)
+		logWriter.Sync()
+		row = &TaskHistory{
+			ExecutionTime: executionTime,
+			ExecutionDate: req.executionDate,
+			Status:        status,
+			Logs:          string(buffer.Bytes()),
+			Manual:        req.manual,
+			Attempt:       attempt,
+			Outputs:       ctx.Outputs(),
+			Params:        req.params,
+		}
+		e.Lock()
+		defer e.Unlock()
+		if err != nil {
+			e.Errors[executionTime] = err
 		}
+		e.History = append(e.History, row)
 	}()
 
-	if notify != nil {
-		<-notify
+	e.ChangeStatus(Running)
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				const size = 64 << 10
+				buf := make([]byte, size)
+				buf = buf[:runtime.Stack(buf, false)]
+				done <- fmt.Errorf("task is panicing: %s", string(buf))
+			}
+		}()
+		done <- e.Task.Run(ctx)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded || c.ctx.Err() == nil {
+			e.leaveRunning(done)
+			return nil, ctx.Err()
+		}
+		// The scheduler is shutting down; the task may still finish within
+		// the grace period.
+		select {
+		case err = <-done:
+		case <-c.interrupted:
+			err = errInterrupted
+		}
 	}
+	return nil, err
+}
 
-	if e.Task.ScheduleOptions().EndDate().Before(executionTime) {
+// recordSkipped adds a Skipped history row for every activation between from
+// and to that the entry's calendars dropped.
+func (c *Atmo) recordSkipped(e *Entry, from, to time.Time) {
+	schedule, ok := e.Schedule.(*calendarSchedule)
+	if !ok || from.IsZero() {
 		return
 	}
-
-	if ctx == nil {
-		ctx, notify, stream = NewBaseContext(executionTime, time.Now(), e.Next, e.Prev, parentStream, logWriter)
+	var skipped []runRequest
+	for _, t := range schedule.skipped(from, to) {
+		skipped = append(skipped, runRequest{executionDate: t})
 	}
+	c.recordHistory(e, Skipped, skipped...)
+}
 
-	log.Printf("[%s] started", e.Task.TaskID().ToString())
-	defer log.Printf("[%s] finished", e.Task.TaskID().ToString())
-	e.ChangeStatus(Running)
-	if err := e.Task.Run(ctx); err != nil {
-		e.ChangeStatus(Failing)
-		logWriter.Sync()
-		e.History = append(e.History, &TaskHistory{
-			ExecutionTime: executionTime,
-			Status:        Success,
-			Logs:          string(buffer.Bytes()),
-		})
-		e.Errors[executionTime] = err
-	} else {
-		e.ChangeStatus(PendingRun)
-		logWriter.Sync()
+// recordHistory adds a history row with the given status for every one of the
+// requested runs, which aren't run.
+func (c *Atmo) recordHistory(e *Entry, status EntryStatus, runs ...runRequest) {
+	e.Lock()
+	defer e.Unlock()
+	for _, r := range runs {
 		e.History = append(e.History, &TaskHistory{
-			ExecutionTime: executionTime,
-			Status:        Success,
-			Logs:          string(buffer.Bytes()),
+			ExecutionTime: r.executionDate,
+			ExecutionDate: r.executionDate,
+			Status:        status,
 		})
 	}
+}
 
-	if isParallel, subTasks := e.Task.SubTasks(); len(subTasks) > 0 {
-		<-notify
-		for _, subTask := range subTasks {
-			executionTime = time.Now()
-			bc, ny, st := NewBaseContext(executionTime, time.Now(), e.Next, e.Prev, stream, logWriter)
-			se := c.entryByTask(subTask)
-			c.runWithRecovery(bc, se, ny, st)
-			if !isParallel {
-				<-ny
-			}
-		}
+// stopOnFailure pauses the entry after a failed run and records why, so it is
+// not scheduled again until it is explicitly resumed. Runs that were
+// interrupted by the scheduler stopping don't count as failures.
+//
+// It is called from a run, while the run loop owns the entry's next
+// activation, so the loop is asked to drop it. If the scheduler stops first,
+// the entry stays paused and isn't given a next activation when it restarts.
+func (c *Atmo) stopOnFailure(e *Entry, row *TaskHistory, executionTime time.Time, err error) {
+	if !e.Task.ScheduleOptions().StopOnFailure() || c.ctx.Err() != nil || row == nil || row.Status == Interrupted {
+		return
+	}
+	e.Lock()
+	e.Paused = true
+	e.Status = Stopped
+	e.StopReason = fmt.Sprintf("stopped on failure of the run at %s: %s", executionTime.Format(time.RFC3339), err)
+	e.Unlock()
+
+	if !c.running {
+		e.Next = time.Time{}
+		return
+	}
+	req := entryRequest{id: e.ID, err: make(chan error, 1)}
+	select {
+	case c.pause <- req:
+		<-req.err
+	case <-c.ctx.Done():
 	}
 }
 
-func (c *Atmo) entryByTask(task Task) *Entry {
+func (c *Atmo) entryByID(id ID) *Entry {
 	for i, e := range c.entries {
-		if e.Task.TaskID() == task.TaskID() {
+		if e.ID == id {
 			return c.entries[i]
 		}
 	}
-	return nil
+	c.childrenMu.Lock()
+	defer c.childrenMu.Unlock()
+	return c.children[id]
+}
+
+// dispatch starts the requested runs of the entry, one after the other, in
+// their own go-routine. Unless the task allows overlapping runs, each run waits
+// for the previous one to finish.
+func (c *Atmo) dispatch(e *Entry, runs ...runRequest) {
+	// The run loop moves Next and Prev on, so the runs get their own copy.
+	for i := range runs {
+		runs[i].nextRunDate, runs[i].prevRunDate = e.Next, e.Prev
+	}
+	if e.Notify == nil {
+		notify := make(chan bool, 1)
+		e.Notify = notify
+		e.Notify <- true
+	}
+
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		for _, r := range runs {
+			if e.Task.ScheduleOptions().AllowOverlap() {
+				if !c.runInSlot(e, r) {
+					return
+				}
+			} else {
+				<-e.Notify
+				ok := c.runInSlot(e, r)
+				e.Notify <- true
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+}
+
+// runInSlot queues the run until the task's pool and the global limit have a
+// free slot, then runs it. It reports false, without running, if the scheduler
+// stopped in the meantime.
+func (c *Atmo) runInSlot(e *Entry, r runRequest) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+	pool := poolOf(e.Task)
+	if pool != "" && !c.slots.hasPool(pool) {
+		c.logf("[%s] pool %s has not been added, the run only counts against the global limit", e.Task.TaskID().ToString(), pool)
+	}
+	e.ChangeStatus(Queued)
+	if !c.slots.acquire(c.ctx, pool, priorityOf(e.Task)) {
+		e.settleStatus(PendingRun)
+		return false
+	}
+	defer c.slots.release(pool)
+	c.runWithRecovery(e, nil, r)
+	return true
 }
 
 // Run the scheduler. this is private just due to the need to synchronize
@@ -214,25 +738,28 @@ func (c *Atmo) run() {
 	// Figure out the next activation times for each entry.
 	now := c.now()
 	for _, entry := range c.entries {
-		entry.Next = entry.Schedule.Next(now)
+		if entry.isPaused() {
+			continue
+		}
+		entry.Next = entry.nextAfter(now)
 	}
 
 	for {
 		// Determine the next entry to run.
 		sort.Sort(byTime(c.entries))
 
-		var timer *time.Timer
+		var timer Timer
 		if len(c.entries) == 0 || c.entries[0].Next.IsZero() {
 			// If there are no entries yet, just sleep - it still handles new entries
 			// and stop requests.
-			timer = time.NewTimer(100000 * time.Hour)
+			timer = c.clock.NewTimer(100000 * time.Hour)
 		} else {
-			timer = time.NewTimer(c.entries[0].Next.Sub(now))
+			timer = c.clock.NewTimer(c.entries[0].Next.Sub(now))
 		}
 
 		for {
 			select {
-			case now = <-timer.C:
+			case now = <-timer.C():
 				now = now.In(c.location)
 				// Run every entry whose next time was less than now
 				for _, e := range c.entries {
@@ -240,31 +767,44 @@ func (c *Atmo) run() {
 						break
 					}
 
-					if e.Notify == nil {
-						notify := make(chan bool, 1)
-						e.Notify = notify
-						e.Notify <- true
+					if e.isPaused() {
+						// Paused outside of the run loop, such as by loading
+						// its stored state or by a failed run.
+						e.Next = time.Time{}
+						continue
 					}
-
-					go func() {
-						if e.Task.ScheduleOptions().AllowOverlap() {
-							c.runWithRecovery(nil, e, nil, nil)
-						} else {
-							<-e.Notify
-							c.runWithRecovery(nil, e, nil, nil)
-							e.Notify <- true
-						}
-					}()
-
-					e.Prev = e.Next
-					e.Next = e.Schedule.Next(now)
+					c.recordSkipped(e, e.Prev, e.Next)
+					c.fire(e, now)
 				}
 
-			case newEntry := <-c.add:
+			case req := <-c.add:
+				timer.Stop()
+				now = c.now()
+				req.entry.Next = req.entry.nextAfter(now)
+				req.err <- c.appendEntry(req.entry)
+
+			case req := <-c.trigger:
+				req.err <- c.triggerEntry(req.id, req.params)
+				continue
+
+			case req := <-c.pause:
+				timer.Stop()
+				now = c.now()
+				req.err <- c.pauseEntry(req.id)
+
+			case req := <-c.resume:
+				timer.Stop()
+				now = c.now()
+				req.err <- c.resumeEntry(req.id)
+
+			case req := <-c.remove:
 				timer.Stop()
 				now = c.now()
-				newEntry.Next = newEntry.Schedule.Next(now)
-				c.entries = append(c.entries, newEntry)
+				req.err <- c.removeEntry(req.id)
+
+			case <-c.backfill:
+				c.backfillEntries(c.now())
+				continue
 
 			case <-c.snapshot:
 				c.snapshot <- c.entrySnapshot()
@@ -295,28 +835,68 @@ func (c *Atmo) Stop() {
 		return
 	}
 	c.stop <- struct{}{}
+	c.cancel()
 	c.running = false
 }
 
-// entrySnapshot returns a copy of the current atmo entry list.
+// Shutdown stops the scheduler from dispatching new runs and cancels the
+// context of the runs in progress. It then waits up to grace for them to
+// return; runs still going after that are recorded as Interrupted. Shutdown
+// returns once every run has been recorded in its entry's history.
+func (c *Atmo) Shutdown(grace time.Duration) {
+	if c.running {
+		c.Stop()
+	} else {
+		// Runs triggered without the run loop still need cancelling.
+		c.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-c.clock.After(grace):
+	}
+	c.interruptOnce.Do(func() { close(c.interrupted) })
+	<-done
+}
+
+// entrySnapshot returns a copy of the current atmo entry list, followed by the
+// entries of sub-tasks.
 func (c *Atmo) entrySnapshot() []*Entry {
 	entries := []*Entry{}
-	for _, e := range c.entries {
+	all := append(append([]*Entry{}, c.entries...), c.Children()...)
+	for _, e := range all {
+		e.RLock()
+		errs := make(map[time.Time]error, len(e.Errors))
+		for t, err := range e.Errors {
+			errs[t] = err
+		}
 		entries = append(entries, &Entry{
-			Schedule: e.Schedule,
-			Status:   e.Status,
-			Next:     e.Next,
-			Prev:     e.Prev,
-			Task:     e.Task,
-			History:  e.History,
-			Errors:   e.Errors,
-			RWMutex:  new(sync.RWMutex),
+			ID:         e.ID,
+			Parent:     e.Parent,
+			Schedule:   e.Schedule,
+			Status:     e.Status,
+			Next:       e.Next,
+			Prev:       e.Prev,
+			Task:       e.Task,
+			Paused:     e.Paused,
+			StopReason: e.StopReason,
+			History:    e.History,
+			Errors:     errs,
+			RWMutex:    new(sync.RWMutex),
 		})
+		e.RUnlock()
 	}
 	return entries
 }
 
 // now returns current time in c location
 func (c *Atmo) now() time.Time {
-	return time.Now().In(c.location)
+	return c.clock.Now().In(c.location)
 }
@@ -0,0 +1,280 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testTask is a Task whose schedule, options, run and sub-tasks are set by the
+// test.
+type testTask struct {
+	id       ID
+	cron     Cron
+	opts     ScheduleOptions
+	run      func(ctx Context) error
+	parallel bool
+	subTasks []Task
+}
+
+func (t *testTask) TaskID() ID {
+	return t.id
+}
+
+func (t *testTask) Run(ctx Context) error {
+	if t.run == nil {
+		return nil
+	}
+	return t.run(ctx)
+}
+
+func (t *testTask) Schedule() Cron {
+	return t.cron
+}
+
+func (t *testTask) ScheduleOptions() ScheduleOptions {
+	if t.opts == nil {
+		return NewScheduleOptions(time.Time{}, NoEndDate(), false, false, false)
+	}
+	return t.opts
+}
+
+func (t *testTask) SubTasks() (bool, []Task) {
+	return t.parallel, t.subTasks
+}
+
+// wait bounds how long the tests wait in real time for a run that is expected,
+// and quiet how long they wait for one that isn't.
+const (
+	wait  = 5 * time.Second
+	quiet = 50 * time.Millisecond
+)
+
+var start = time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+
+var errBoom = errors.New("boom")
+
+func nextRun(t *testing.T, runs <-chan time.Time) time.Time {
+	t.Helper()
+	select {
+	case date := <-runs:
+		return date
+	case <-time.After(wait):
+		t.Fatal("timed out waiting for a run")
+	}
+	return time.Time{}
+}
+
+func noRun(t *testing.T, runs <-chan time.Time) {
+	t.Helper()
+	select {
+	case date := <-runs:
+		t.Fatalf("unexpected run for %s", date)
+	case <-time.After(quiet):
+	}
+}
+
+// settle waits for every dispatched run of c to return.
+func settle(t *testing.T, c *Atmo) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		c.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(wait):
+		t.Fatal("timed out waiting for the runs to finish")
+	}
+}
+
+func TestRunFiresOnSchedule(t *testing.T) {
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	runs := make(chan time.Time, 10)
+	task := &testTask{id: "hourly", cron: "@hourly", run: func(ctx Context) error {
+		runs <- ctx.ExecutionDate()
+		return nil
+	}}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	defer c.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Minute)
+	noRun(t, runs)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if date := nextRun(t, runs); !date.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected the run for %s, got %s", start.Add(time.Hour), date)
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	if date := nextRun(t, runs); !date.Equal(start.Add(2 * time.Hour)) {
+		t.Errorf("expected the run for %s, got %s", start.Add(2*time.Hour), date)
+	}
+}
+
+func TestRunOverlap(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowOverlap bool
+	}{
+		{"allowed", true},
+		{"not allowed", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := NewFakeClock(start)
+			c := NewWithLocation(time.UTC, clock)
+			runs := make(chan time.Time, 10)
+			release := make(chan struct{})
+			task := &testTask{
+				id:   "overlap",
+				cron: "@every 1m",
+				opts: NewScheduleOptions(time.Time{}, NoEndDate(), false, test.allowOverlap, false),
+				run: func(ctx Context) error {
+					runs <- ctx.ExecutionDate()
+					<-release
+					return nil
+				},
+			}
+			if err := c.AddTask(task.Schedule(), task); err != nil {
+				t.Fatal(err)
+			}
+			c.Start()
+			defer c.Stop()
+
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+			if date := nextRun(t, runs); !date.Equal(start.Add(time.Minute)) {
+				t.Fatalf("expected the run for %s, got %s", start.Add(time.Minute), date)
+			}
+
+			// The first run is still going when the second comes due.
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+			if !test.allowOverlap {
+				noRun(t, runs)
+			}
+			close(release)
+			if date := nextRun(t, runs); !date.Equal(start.Add(2 * time.Minute)) {
+				t.Fatalf("expected the run for %s, got %s", start.Add(2*time.Minute), date)
+			}
+		})
+	}
+}
+
+func TestRunSubTaskChain(t *testing.T) {
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	order := make(chan string, 10)
+	subTask := func(id ID) Task {
+		return &testTask{id: id, run: func(ctx Context) error {
+			batch, err := ctx.ParentOutputs().String("batch")
+			if err != nil {
+				return err
+			}
+			order <- string(id) + ":" + batch
+			return nil
+		}}
+	}
+	task := &testTask{
+		id:   "parent",
+		cron: "@hourly",
+		run: func(ctx Context) error {
+			order <- "parent"
+			return ctx.SetOutput("batch", "42")
+		},
+		subTasks: []Task{subTask("extract"), subTask("load")},
+	}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	for _, expected := range []string{"parent", "extract:42", "load:42"} {
+		select {
+		case got := <-order:
+			if got != expected {
+				t.Fatalf("expected %s to run next, got %s", expected, got)
+			}
+		case <-time.After(wait):
+			t.Fatalf("timed out waiting for %s", expected)
+		}
+	}
+	c.Shutdown(wait)
+
+	for _, id := range []ID{"parent", "parent/extract", "parent/load"} {
+		e := c.entryByID(id)
+		if e == nil {
+			t.Fatalf("no entry for %s", id)
+		}
+		if len(e.History) != 1 || e.History[0].Status != Success {
+			t.Errorf("expected %s to have run once successfully, got %d runs", id, len(e.History))
+			continue
+		}
+		if date := e.History[0].ExecutionDate; !date.Equal(start.Add(time.Hour)) {
+			t.Errorf("expected %s to run for %s, got %s", id, start.Add(time.Hour), date)
+		}
+	}
+}
+
+func TestStopOnFailure(t *testing.T) {
+	clock := NewFakeClock(start)
+	c := NewWithLocation(time.UTC, clock)
+	runs := make(chan time.Time, 10)
+	task := &testTask{
+		id:   "failing",
+		cron: "@hourly",
+		opts: NewScheduleOptions(time.Time{}, NoEndDate(), true, false, false),
+		run: func(ctx Context) error {
+			runs <- ctx.ExecutionDate()
+			return errBoom
+		},
+	}
+	if err := c.AddTask(task.Schedule(), task); err != nil {
+		t.Fatal(err)
+	}
+	c.Start()
+	defer c.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	nextRun(t, runs)
+
+	deadline := time.Now().Add(wait)
+	for {
+		e := c.Entries()[0]
+		if e.Status == Stopped && e.Next.IsZero() {
+			if !e.Paused || e.StopReason == "" {
+				t.Errorf("expected a paused entry with a stop reason, got %+v", e)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the entry to stop with no next run, got %s next at %s", e.Status, e.Next)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Hour)
+	noRun(t, runs)
+}
+
+func TestShutdownTwice(t *testing.T) {
+	c := NewWithLocation(time.UTC, NewFakeClock(start))
+	c.Start()
+	for i := 0; i < 10; i++ {
+		c.Shutdown(0)
+	}
+}
@@ -2,20 +2,27 @@ package scheduler
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"log"
 	"time"
 )
 
 var sch *scheduler
 var schTaskBuffer = make(chan Task, 5000)
+var schPools = map[string]int{}
+var schMaxConcurrency int
 
 type scheduler struct {
 	atmo *Atmo
+	db   Store
 }
 
 func InitScheduler(db Store) (err error) {
-	sch = &scheduler{NewCron()}
+	sch = &scheduler{atmo: NewCron(), db: db}
+	sch.atmo.SetMaxConcurrency(schMaxConcurrency)
+	for name, size := range schPools {
+		sch.atmo.AddPool(name, size)
+	}
 	sch.atmo.Start()
 
 	go func() {
@@ -23,17 +30,35 @@ func InitScheduler(db Store) (err error) {
 		for {
 			select {
 			case t := <-schTaskBuffer:
-				sched, pErr := ParseStandard(t.Schedule())
+				sched, pErr := scheduleFor(t)
 				if pErr != nil {
-					errors.As(pErr, &err)
+					log.Printf("failed to parse schedule of %s: %s", t.TaskID().ToString(), pErr.Error())
+					err = pErr
+					continue
+				}
+				if schErr := sch.atmo.Schedule(sched, t); schErr != nil {
+					log.Printf("failed to schedule %s: %s", t.TaskID().ToString(), schErr.Error())
+					err = schErr
 				}
-				sch.atmo.Schedule(sched, t)
 			case <-ticker.C:
 				if len(schTaskBuffer) == 0 && len(sch.atmo.entries) > 0 {
+					// Entries whose state failed to load keep running on their
+					// schedule; the others still get theirs applied.
 					if updateErr := db.UpdateInMemoryEntriesFromStorage(context.TODO(), sch.atmo.entries); updateErr != nil {
 						log.Printf("failed to update (entry|entries): %s", updateErr.Error())
-						errors.As(updateErr, &err)
-						return
+						err = updateErr
+					}
+					if updateErr := db.UpdateInMemoryEntriesFromStorage(context.TODO(), sch.atmo.Children()); updateErr != nil {
+						log.Printf("failed to update sub-task (entry|entries): %s", updateErr.Error())
+					}
+					for _, e := range sch.atmo.Entries() {
+						if e.Paused {
+							_ = sch.atmo.Pause(e.ID)
+						}
+					}
+					sch.atmo.Backfill()
+					if restoreErr := restoreOnceEntries(db); restoreErr != nil {
+						log.Printf("failed to restore one-shot entries: %s", restoreErr.Error())
 					}
 					return
 				}
@@ -44,11 +69,16 @@ func InitScheduler(db Store) (err error) {
 	return nil
 }
 
-func StopScheduler(db Store) error {
-	sch.atmo.Stop()
+// StopScheduler shuts the scheduler down, giving runs in progress up to grace
+// to finish, and then saves every entry to the store.
+func StopScheduler(db Store, grace time.Duration) error {
+	sch.atmo.Shutdown(grace)
 	ctx := context.TODO()
 	defer db.Close(ctx)
-	return db.UpdateEntries(ctx, sch.atmo.entries)
+	if err := db.UpdateEntries(ctx, sch.atmo.entries); err != nil {
+		return err
+	}
+	return db.UpdateEntries(ctx, sch.atmo.Children())
 }
 
 func ScheduleTask(t Task) {
@@ -57,22 +87,177 @@ func ScheduleTask(t Task) {
 	}
 }
 
+// SetMaxConcurrency limits how many task runs execute at once. Like
+// ScheduleTask, it may be called before the scheduler is initialized.
+func SetMaxConcurrency(max int) {
+	if sch == nil {
+		schMaxConcurrency = max
+		return
+	}
+	sch.atmo.SetMaxConcurrency(max)
+}
+
+// AddPool creates a named worker pool with the given number of slots. Like
+// ScheduleTask, it may be called from a task package's init.
+func AddPool(name string, size int) {
+	if sch == nil {
+		schPools[name] = size
+		return
+	}
+	sch.atmo.AddPool(name, size)
+}
+
+// TriggerTask runs the scheduled task with the given ID now, outside of its schedule.
+func TriggerTask(id ID) error {
+	return sch.atmo.Trigger(id)
+}
+
+// TriggerTaskWithParams is like TriggerTask, but runs the task with params
+// overriding its DefaultParams.
+func TriggerTaskWithParams(id ID, params Params) error {
+	return sch.atmo.TriggerWithParams(id, params)
+}
+
+// PauseTask stops the task with the given ID from being scheduled and saves
+// the paused state so it survives a restart.
+func PauseTask(id ID) error {
+	if err := sch.atmo.Pause(id); err != nil {
+		return err
+	}
+	return saveEntry(id)
+}
+
+// ResumeTask schedules a paused task again and saves the resumed state.
+func ResumeTask(id ID) error {
+	if err := sch.atmo.Resume(id); err != nil {
+		return err
+	}
+	return saveEntry(id)
+}
+
+// RemoveTask takes the task with the given ID out of the running scheduler.
+func RemoveTask(id ID) error {
+	return sch.atmo.Remove(id)
+}
+
+// ScheduleTaskOnce runs the scheduled task with the given ID a single time at
+// the given time, besides its schedule. The one-shot entry is saved so it
+// survives a restart. It returns the ID of the new entry.
+func ScheduleTaskOnce(id ID, at time.Time) (ID, error) {
+	for _, e := range sch.atmo.Entries() {
+		if e.ID == id {
+			onceID, err := sch.atmo.ScheduleOnce(at, e.Task)
+			if err != nil {
+				return "", err
+			}
+			return onceID, saveEntry(onceID)
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+}
+
+// restoreOnceEntries schedules the one-shot entries saved in the store that
+// have not run yet. Those whose time passed while the scheduler was down run
+// right away.
+func restoreOnceEntries(db Store) error {
+	records, err := db.OnceEntries(context.TODO())
+	if err != nil {
+		return err
+	}
+	tasks := make(map[ID]Task)
+	scheduled := make(map[ID]bool)
+	for _, e := range sch.atmo.Entries() {
+		tasks[e.Task.TaskID()] = e.Task
+		scheduled[e.ID] = true
+	}
+	now := sch.atmo.now()
+	for _, r := range records {
+		if !r.Prev.IsZero() || scheduled[r.ID] {
+			continue
+		}
+		task, ok := tasks[r.TaskID]
+		if !ok {
+			log.Printf("not restoring one-shot entry %s: task %s is not registered", r.ID, r.TaskID)
+			continue
+		}
+		at := r.At
+		if !at.After(now) {
+			at = now.Add(time.Second)
+		}
+		if err := sch.atmo.schedule(r.ID, OnceSchedule{At: at}, task); err != nil {
+			log.Printf("not restoring one-shot entry %s: %s", r.ID, err.Error())
+		}
+	}
+	return nil
+}
+
+// saveEntry writes the current state of the entry with the given ID to the store.
+func saveEntry(id ID) error {
+	for _, e := range sch.atmo.Entries() {
+		if e.ID == id {
+			return sch.db.UpdateEntries(context.TODO(), []*Entry{e})
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrTaskNotFound, id)
+}
+
 func TaskList() []DisplayTask {
 	var taskList []DisplayTask
 	entries := sch.atmo.entrySnapshot()
+	now := sch.atmo.now()
 	for _, e := range entries {
 		var lastRun time.Time
 		if len(e.History) > 0 {
 			lastRun = e.History[len(e.History)-1].ExecutionTime
 		}
-		taskList = append(taskList, DisplayTask{
-			ID:       string(e.Task.TaskID()),
-			Status:   string(e.Status),
-			Schedule: e.Task.Schedule(),
-			NextRun:  e.Next,
-			LastRun:  lastRun,
-			History:  e.History,
-		})
+		schedules, exclusions := cronsOf(e.Task)
+		location := locationOf(e.Schedule)
+		if location == nil {
+			location = sch.atmo.Location()
+		}
+		task := DisplayTask{
+			ID:         string(e.ID),
+			Status:     string(e.displayStatus(now)),
+			Schedule:   e.Task.Schedule(),
+			NextRun:    e.Next,
+			LastRun:    lastRun,
+			History:    e.History,
+			StopReason: e.StopReason,
+			Location:   location.String(),
+			Schedules:  schedules,
+			Exclusions: exclusions,
+		}
+		if once, ok := e.Schedule.(OnceSchedule); ok {
+			task.Schedule, task.Schedules, task.Exclusions = "", nil, nil
+			task.OnceAt = once.At
+		}
+		if e.Parent != "" {
+			task.Schedule, task.Schedules, task.Exclusions = "", nil, nil
+			task.Parent = string(e.Parent)
+		}
+		taskList = append(taskList, task)
 	}
 	return taskList
 }
+
+// locationOf returns the time zone the schedule is worked out in, or nil if
+// it follows the scheduler's location.
+func locationOf(s Schedule) *time.Location {
+	switch s := s.(type) {
+	case *SpecSchedule:
+		return s.Location
+	case *calendarSchedule:
+		return locationOf(s.Schedule)
+	case *jitterSchedule:
+		return locationOf(s.Schedule)
+	case ExcludeSchedule:
+		return locationOf(s.Include)
+	case UnionSchedule:
+		for _, member := range s {
+			if loc := locationOf(member); loc != nil {
+				return loc
+			}
+		}
+	}
+	return nil
+}
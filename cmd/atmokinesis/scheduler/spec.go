@@ -6,6 +6,30 @@ import "time"
 // traditional crontab specification. It is computed initially and stored as bit sets.
 type SpecSchedule struct {
 	Second, Minute, Hour, Dom, Month, Dow uint64
+
+	// Location overrides the time zone the schedule is worked out in, as set by
+	// a CRON_TZ= or TZ= prefix. A nil Location uses the zone of the time passed
+	// to Next.
+	Location *time.Location
+
+	// DST decides how wall clock times skipped by a daylight saving time
+	// transition are handled.
+	DST DSTPolicy
+
+	// LastDom matches the last day of the month ("L"), LastWeekdayDom the last
+	// weekday of the month ("LW"), and bit n of NearestWeekdayDom the weekday
+	// nearest to day n of the month ("nW").
+	LastDom, LastWeekdayDom bool
+	NearestWeekdayDom       uint64
+
+	// Bit 7*(n-1)+d of NthDow matches the nth day d of the week in the month
+	// ("d#n"), and bit d of LastDow the last day d of the week in the month
+	// ("dL").
+	NthDow, LastDow uint64
+
+	// Years restricts the schedule to the given years. A nil Years matches
+	// every year.
+	Years map[int]bool
 }
 
 // bounds provides a range of acceptable values (plus a map of name to value).
@@ -34,7 +58,8 @@ var (
 		"nov": 11,
 		"dec": 12,
 	}}
-	dow = bounds{0, 6, map[string]uint{
+	years = bounds{1970, 2099, nil}
+	dow   = bounds{0, 6, map[string]uint{
 		"sun": 0,
 		"mon": 1,
 		"tue": 2,
@@ -52,9 +77,55 @@ const (
 
 // Next returns the next time this schedule is activated, greater than the given
 // time.  If no time can be found to satisfy the schedule, return the zero time.
+//
+// The schedule is matched against the wall clock, so daylight saving time
+// transitions are handled as described by DSTPolicy.
 func (s *SpecSchedule) Next(t time.Time) time.Time {
+	// Convert the given time into the schedule's time zone, if one is given.
+	// Remember the original zone so the result can be converted back.
+	origLocation := t.Location()
+	loc := origLocation
+	if s.Location != nil {
+		loc = s.Location
+	}
+	t = t.In(loc)
+
+	// Search the wall clock readings that match the schedule, and turn each
+	// into an instant in the location.
+	wall := wallClock(t)
+	yearLimit := wall.Year() + 5
+	for year := range s.Years {
+		if year > yearLimit {
+			yearLimit = year
+		}
+	}
+	for {
+		wall = s.nextWallClock(wall, yearLimit)
+		if wall.IsZero() {
+			return time.Time{}
+		}
+		candidates := instants(wall, loc)
+		if len(candidates) == 0 {
+			// The reading falls inside a DST gap.
+			if s.DST == DSTSkip {
+				continue
+			}
+			candidates = append(candidates, gapEnd(wall, loc))
+		}
+		for _, next := range candidates {
+			if next.After(t) {
+				return next.In(origLocation)
+			}
+		}
+	}
+}
+
+// nextWallClock returns the next wall clock reading after t that matches the
+// schedule, or the zero time if there is none before yearLimit has passed.
+// The reading is given as a UTC time.
+func (s *SpecSchedule) nextWallClock(t time.Time, yearLimit int) time.Time {
 	// General approach:
-	// For Month, Day, Hour, Minute, Second:
+	// For Year, Month, Day, Hour, Minute, Second:
 	// Check if the time value matches.  If yes, continue to the next field.
 	// If the field doesn't match the schedule, then increment the field until it matches.
 	// While incrementing the field, a wrap-around brings it back to the beginning
@@ -67,14 +138,21 @@ func (s *SpecSchedule) Next(t time.Time) time.Time {
 	// This flag indicates whether a field has been incremented.
 	added := false
 
-	// If no time is found within five years, return zero.
-	yearLimit := t.Year() + 5
-
 WRAP:
 	if t.Year() > yearLimit {
 		return time.Time{}
 	}
 
+	// Find the first applicable year.
+	for s.Years != nil && !s.Years[t.Year()] {
+		added = true
+		t = time.Date(t.Year()+1, time.January, 1, 0, 0, 0, 0, t.Location())
+
+		if t.Year() > yearLimit {
+			return time.Time{}
+		}
+	}
+
 	// Find the first applicable month.
 	// If it's this month, then do nothing.
 	for 1<<uint(t.Month())&s.Month == 0 {
@@ -148,11 +226,66 @@ WRAP:
 // restrictions are satisfied by the given time.
 func dayMatches(s *SpecSchedule, t time.Time) bool {
 	var (
-		domMatch bool = 1<<uint(t.Day())&s.Dom > 0
-		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0
+		domMatch bool = 1<<uint(t.Day())&s.Dom > 0 || domExtraMatches(s, t)
+		dowMatch bool = 1<<uint(t.Weekday())&s.Dow > 0 || dowExtraMatches(s, t)
 	)
 	if s.Dom&starBit > 0 || s.Dow&starBit > 0 {
 		return domMatch && dowMatch
 	}
 	return domMatch || dowMatch
 }
+
+// domExtraMatches returns true if the given time matches one of the "L", "LW"
+// or "nW" terms of the schedule's day-of-month.
+func domExtraMatches(s *SpecSchedule, t time.Time) bool {
+	last := daysIn(t)
+	if s.LastDom && t.Day() == last {
+		return true
+	}
+	if s.LastWeekdayDom && t.Day() == nearestWeekday(t, last) {
+		return true
+	}
+	if s.NearestWeekdayDom == 0 {
+		return false
+	}
+	for day := 1; day <= last; day++ {
+		if 1<<uint(day)&s.NearestWeekdayDom > 0 && t.Day() == nearestWeekday(t, day) {
+			return true
+		}
+	}
+	return false
+}
+
+// dowExtraMatches returns true if the given time matches one of the "d#n" or
+// "dL" terms of the schedule's day-of-week.
+func dowExtraMatches(s *SpecSchedule, t time.Time) bool {
+	weekday := uint(t.Weekday())
+	nth := uint(t.Day()-1) / 7
+	if 1<<(7*nth+weekday)&s.NthDow > 0 {
+		return true
+	}
+	return 1<<weekday&s.LastDow > 0 && t.Day()+7 > daysIn(t)
+}
+
+// daysIn returns the number of days in the month of the given time.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// nearestWeekday returns the Monday to Friday closest to the given day of the
+// month of t, without leaving the month.
+func nearestWeekday(t time.Time, day int) int {
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, t.Location()).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == daysIn(t) {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
@@ -15,9 +16,19 @@ type Store interface {
 	UpdateEntries(c context.Context, entries []*Entry) (err error)
 	AddEntries(c context.Context, entries []*Entry) (err error)
 	UpdateInMemoryEntriesFromStorage(c context.Context, entries []*Entry) (err error)
+	OnceEntries(c context.Context) (records []OnceRecord, err error)
 	Close(c context.Context) error
 }
 
+// OnceRecord describes a one-shot entry saved in the Store, see
+// Atmo.ScheduleOnce.
+type OnceRecord struct {
+	ID     ID
+	TaskID ID
+	At     time.Time
+	Prev   time.Time // the zero time if the entry has not run yet
+}
+
 const (
 	database   = "atmokinesis"
 	collection = "entries"
@@ -46,19 +57,19 @@ func (s mongoStore) UpdateEntries(c context.Context, entries []*Entry) (err erro
 		ctx, cancel := context.WithTimeout(c, 60*time.Second)
 		data, marshalErr := e.MarshalToBSON()
 		if marshalErr != nil {
-			errors.As(fmt.Errorf("update to entry had an issue: %w", marshalErr), &err)
+			err = fmt.Errorf("update to entry had an issue: %w", marshalErr)
 			cancel()
 			continue
 		}
-		res, updateErr := s.entryCollection.UpdateOne(ctx, bson.D{{"_id", e.Task.TaskID()}}, bson.M{"$addToSet": data})
+		res, updateErr := s.entryCollection.UpdateOne(ctx, bson.D{{"_id", e.ID}}, bson.M{"$addToSet": data, "$set": e.MarshalStateToBSON()})
 		if updateErr != nil {
-			errors.As(fmt.Errorf("update to entry had an issue: %w", updateErr), &err)
+			err = fmt.Errorf("update to entry had an issue: %w", updateErr)
 			cancel()
 			continue
 		}
 		if res.MatchedCount == 0 {
 			if addErr := s.AddEntries(c, []*Entry{e}); addErr != nil {
-				errors.As(fmt.Errorf("adding entry failed: %w", addErr), &err)
+				err = fmt.Errorf("adding entry failed: %w", addErr)
 			}
 		}
 		cancel()
@@ -71,14 +82,17 @@ func (s mongoStore) AddEntries(c context.Context, entries []*Entry) (err error)
 		ctx, cancel := context.WithTimeout(c, 60*time.Second)
 		data, marshalErr := e.MarshalToBSON()
 		if marshalErr != nil {
-			errors.As(fmt.Errorf("adding entry had an issue: %w", marshalErr), &err)
+			err = fmt.Errorf("adding entry had an issue: %w", marshalErr)
 			cancel()
 			continue
 		}
-		data["_id"] = e.Task.TaskID()
+		data["_id"] = e.ID
+		for key, val := range e.MarshalStateToBSON() {
+			data[key] = val
+		}
 		_, updateErr := s.entryCollection.InsertOne(ctx, data)
 		if updateErr != nil {
-			errors.As(fmt.Errorf("adding entry had an issue: %w", updateErr), &err)
+			err = fmt.Errorf("adding entry had an issue: %w", updateErr)
 		}
 		cancel()
 	}
@@ -90,20 +104,25 @@ func (s mongoStore) UpdateInMemoryEntriesFromStorage(c context.Context, entries
 		ctx, cancel := context.WithTimeout(c, 60*time.Second)
 		body, bodyErr := e.MarshalToBSON()
 		if bodyErr != nil {
-			errors.As(fmt.Errorf("loading entry from store failed on decoding: %w", bodyErr), &err)
+			err = fmt.Errorf("loading entry from store failed on decoding: %w", bodyErr)
 			cancel()
 			continue
 		}
 
-		res := s.entryCollection.FindOne(ctx, bson.D{{"_id", e.Task.TaskID()}})
+		res := s.entryCollection.FindOne(ctx, bson.D{{"_id", e.ID}})
+		if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+			// The entry has not been saved yet, so there is no state to load.
+			cancel()
+			continue
+		}
 		if decErr := res.Decode(&body); decErr != nil {
-			errors.As(fmt.Errorf("loading entry from store failed on decoding: %w", decErr), &err)
+			err = fmt.Errorf("loading entry from store failed on decoding: %w", decErr)
 			cancel()
 			continue
 		}
 
-		if unmarshalErr := UnmarshalBSON(body, e); err != nil {
-			errors.As(fmt.Errorf("loading entry from store failed on updating entry: %w", unmarshalErr), &err)
+		if unmarshalErr := UnmarshalBSON(body, e); unmarshalErr != nil {
+			err = fmt.Errorf("loading entry from store failed on updating entry: %w", unmarshalErr)
 			cancel()
 			continue
 		}
@@ -112,6 +131,32 @@ func (s mongoStore) UpdateInMemoryEntriesFromStorage(c context.Context, entries
 	return err
 }
 
+func (s mongoStore) OnceEntries(c context.Context) (records []OnceRecord, err error) {
+	ctx, cancel := context.WithTimeout(c, 60*time.Second)
+	defer cancel()
+	cursor, err := s.entryCollection.Find(ctx, bson.M{"once_at": bson.M{"$exists": true}})
+	if err != nil {
+		return nil, fmt.Errorf("loading one-shot entries failed: %w", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if decErr := cursor.Decode(&doc); decErr != nil {
+			return records, fmt.Errorf("loading one-shot entries failed on decoding: %w", decErr)
+		}
+		id, _ := doc["_id"].(string)
+		taskID, _ := doc["task_id"].(string)
+		at, _ := doc["once_at"].(primitive.DateTime)
+		prev, _ := doc["prev"].(primitive.DateTime)
+		record := OnceRecord{ID: ID(id), TaskID: ID(taskID), At: at.Time()}
+		if prev > 0 {
+			record.Prev = prev.Time()
+		}
+		records = append(records, record)
+	}
+	return records, cursor.Err()
+}
+
 func (s mongoStore) Close(c context.Context) error {
 	ctx, cancel := context.WithTimeout(c, 160*time.Second)
 	defer cancel()
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// childSchedule is the schedule of the entry a sub-task runs under. It never
+// activates, as the sub-task only runs with its parent or when triggered.
+type childSchedule struct{}
+
+// Next always returns the zero time.
+func (childSchedule) Next(time.Time) time.Time {
+	return time.Time{}
+}
+
+// childID returns the ID of the entry the sub-task runs under as a child of
+// the parent entry. The children of a task's one-shot entries are those of its
+// own schedule.
+func childID(parent *Entry, task Task) ID {
+	prefix := parent.ID
+	if parent.Parent == "" {
+		prefix = parent.Task.TaskID()
+	}
+	return prefix + "/" + task.TaskID()
+}
+
+// childEntry returns the entry the sub-task runs under as a child of the
+// parent entry, creating it the first time. A sub-task that is also scheduled
+// on its own keeps a separate entry for that.
+func (c *Atmo) childEntry(parent *Entry, task Task) *Entry {
+	id := childID(parent, task)
+	c.childrenMu.Lock()
+	defer c.childrenMu.Unlock()
+	if e, ok := c.children[id]; ok {
+		return e
+	}
+	e := newChildEntry(id, parent, task)
+	c.children[id] = e
+	return e
+}
+
+// newChildEntry returns a new entry with the given ID for the sub-task, as a
+// child of the parent entry.
+func newChildEntry(id ID, parent *Entry, task Task) *Entry {
+	return &Entry{
+		ID:       id,
+		Parent:   parent.ID,
+		Schedule: childSchedule{},
+		Status:   PendingRun,
+		Task:     task,
+		Errors:   make(map[time.Time]error),
+		RWMutex:  new(sync.RWMutex),
+	}
+}
+
+// Children returns the entries of sub-tasks, by ID. They aren't among the
+// entries the run loop schedules, so they are saved and loaded on their own.
+func (c *Atmo) Children() []*Entry {
+	c.childrenMu.Lock()
+	defer c.childrenMu.Unlock()
+	children := make([]*Entry, 0, len(c.children))
+	for _, e := range c.children {
+		children = append(children, e)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].ID < children[j].ID })
+	return children
+}
+
+// addChildren adds the entries of the sub-tasks of the entry, and of theirs,
+// so their history can be loaded before they first run.
+func (c *Atmo) addChildren(e *Entry) {
+	_, subTasks := e.Task.SubTasks()
+	for _, subTask := range subTasks {
+		c.addChildren(c.childEntry(e, subTask))
+	}
+}
@@ -32,7 +32,10 @@ type Task interface {
 	SubTasks() (isParallel bool, tasks []Task)
 }
 
+// Context is handed to every run of a Task. It is cancelled when the run times
+// out or the scheduler stops, so long running tasks should watch Done.
 type Context interface {
+	context.Context
 	ExecutionDate() time.Time
 	StartDate() time.Time
 	NextRunDate() time.Time
@@ -40,6 +43,34 @@ type Context interface {
 	LogWriteSyncer() WriteSyncer
 	StreamToSubTasks(out interface{})
 	NotifySubTasks()
+
+	// SetOutput publishes a named output of the run, encoded as JSON. Its
+	// sub-tasks read it through ParentOutputs, and the next runs of the task
+	// through PreviousOutputs.
+	SetOutput(name string, value interface{}) error
+	// Outputs returns the outputs the run has published so far.
+	Outputs() Outputs
+	// ParentOutputs returns the outputs of the parent run of a sub-task.
+	ParentOutputs() Outputs
+	// PreviousOutputs returns the outputs of the task's last successful run.
+	PreviousOutputs() Outputs
+
+	// MapSubTask spawns an instance of template for every key, such as one per
+	// file the run discovered. The instances run once the run has succeeded,
+	// one at a time, or at once up to the task's MapLimiter cap if its
+	// sub-tasks are parallel. Their history is grouped under the run's.
+	MapSubTask(template Task, keys ...string)
+	// MapIndex returns the position of the key a mapped instance was spawned
+	// for among the keys given to MapSubTask, or -1 if the run isn't one.
+	MapIndex() int
+	// MapKey returns the key a mapped instance was spawned for, or "" if the
+	// run isn't one.
+	MapKey() string
+
+	// Params returns the parameters of the run: the task's DefaultParams,
+	// overridden by those it was triggered with. Sub-tasks get the parameters
+	// of their parent run, over their own defaults.
+	Params() Params
 }
 
 type WriteSyncer interface {
@@ -79,10 +110,33 @@ func DockerRunner(ctx context.Context, containerName string, containerConfig *co
 		return
 	}
 
+	// stop stops the container once ctx is done, and reports why.
+	stop := func() {
+		if stopErr := c.ContainerStop(context.Background(), resp.ID, nil); stopErr != nil {
+			containerErr <- fmt.Errorf("failed to stop container after %s: %w", ctx.Err(), stopErr)
+			return
+		}
+		containerErr <- ctx.Err()
+	}
+
 	go func() {
 		for {
-			var jsbody types.ContainerJSON
-			if jsbody, err = c.ContainerInspect(ctx, resp.ID); err != nil {
+			select {
+			case <-ctx.Done():
+				stop()
+				return
+			default:
+			}
+
+			jsbody, inspectErr := c.ContainerInspect(ctx, resp.ID)
+			if inspectErr != nil {
+				// Cancelling ctx mostly shows up here, as the loop spends
+				// its time waiting on ContainerInspect.
+				if ctx.Err() != nil {
+					stop()
+					return
+				}
+				containerErr <- fmt.Errorf("failed to inspect container: %w", inspectErr)
 				return
 			}
 			if len(jsbody.State.FinishedAt) > 0 {
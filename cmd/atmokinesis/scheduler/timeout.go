@@ -0,0 +1,66 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouter is an optional interface, see ScheduleOptions, that cancels runs
+// that take longer than Timeout. A zero Timeout never cancels.
+//
+// A timed out run is recorded as TimedOut right away, but its Run can't be
+// stopped from outside and keeps going until it returns, so Run should watch
+// ctx.Done. Unless the task allows overlapping runs, its next run or retry
+// waits for it.
+type Timeouter interface {
+	Timeout() time.Duration
+}
+
+// timeoutOf returns the run timeout of the task, or zero if it has none.
+func timeoutOf(task Task) time.Duration {
+	for _, v := range optionSources(task) {
+		if t, ok := v.(Timeouter); ok {
+			return t.Timeout()
+		}
+	}
+	return 0
+}
+
+// runContext returns the context a single run of task executes in. It is
+// cancelled when parent is, or once the task's timeout has passed.
+func runContext(parent context.Context, task Task) (context.Context, context.CancelFunc) {
+	if timeout := timeoutOf(task); timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// leaveRunning records that the timed out run of the entry whose Run reports
+// on done is still going.
+func (e *Entry) leaveRunning(done <-chan error) {
+	returned := make(chan struct{})
+	e.Lock()
+	e.timedOut = returned
+	e.Unlock()
+	go func() {
+		<-done
+		close(returned)
+	}()
+}
+
+// waitForTimedOut waits for the last timed out run of the entry to return. It
+// reports false if the scheduler stopped in the meantime.
+func (c *Atmo) waitForTimedOut(e *Entry) bool {
+	e.RLock()
+	returned := e.timedOut
+	e.RUnlock()
+	if returned == nil {
+		return true
+	}
+	select {
+	case <-returned:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
@@ -3,10 +3,16 @@ package scheduler
 import "time"
 
 type DisplayTask struct {
-	ID       string         `json:"id,omitempty"`
-	Status   string         `json:"status,omitempty"`
-	Schedule Cron           `json:"schedule,omitempty"`
-	NextRun  time.Time      `json:"next_run,omitempty"`
-	LastRun  time.Time      `json:"last_run,omitempty"`
-	History  []*TaskHistory `json:"history,omitempty"`
+	ID         string         `json:"id,omitempty"`
+	Status     string         `json:"status,omitempty"`
+	Schedule   Cron           `json:"schedule,omitempty"`
+	NextRun    time.Time      `json:"next_run,omitempty"`
+	LastRun    time.Time      `json:"last_run,omitempty"`
+	History    []*TaskHistory `json:"history,omitempty"`
+	StopReason string         `json:"stop_reason,omitempty"`
+	Location   string         `json:"location,omitempty"`
+	Schedules  []Cron         `json:"schedules,omitempty"`
+	Exclusions []Cron         `json:"exclusions,omitempty"`
+	OnceAt     time.Time      `json:"once_at,omitempty"`
+	Parent     string         `json:"parent,omitempty"`
 }
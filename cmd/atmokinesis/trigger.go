@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/insubordination/atmokinesis/cmd/atmokinesis/scheduler"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// paramFlag collects the repeated name=value flags of the parameters of a run.
+type paramFlag scheduler.Params
+
+func (p paramFlag) String() string {
+	var pairs []string
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (p paramFlag) Set(pair string) error {
+	nameAndValue := strings.SplitN(pair, "=", 2)
+	if len(nameAndValue) != 2 || nameAndValue[0] == "" {
+		return fmt.Errorf("expected name=value, got %q", pair)
+	}
+	p[nameAndValue[0]] = nameAndValue[1]
+	return nil
+}
+
+// trigger runs a task now through the web API of a running scheduler, for
+// example
+//
+//	atmokinesis trigger -p region=eu -p dry_run=true ingest
+//
+// and returns the exit code.
+func trigger(args []string) int {
+	flags := flag.NewFlagSet("trigger", flag.ContinueOnError)
+	addr := flags.String("addr", fmt.Sprintf("http://127.0.0.1:%d", servePort), "Address of the scheduler's web API.")
+	params := make(paramFlag)
+	flags.Var(params, "p", "Parameter of the run as name=value, may be repeated.")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: atmokinesis trigger [flags] <task id>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	endpoint := strings.TrimRight(*addr, "/") + "/tasks/trigger?id=" + url.QueryEscape(flags.Arg(0))
+	resp, err := http.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		message, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "error: %s: %s", resp.Status, message)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/insubordination/atmokinesis/cmd/atmokinesis/scheduler"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTrigger(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		status int
+		// The request expected, if one is sent.
+		id     string
+		params scheduler.Params
+		code   int
+	}{
+		{"params", []string{"-p", "region=eu", "-p", "dry_run=true", "ingest"}, http.StatusAccepted,
+			"ingest", scheduler.Params{"region": "eu", "dry_run": "true"}, 0},
+		{"no params", []string{"ingest"}, http.StatusAccepted,
+			"ingest", scheduler.Params{}, 0},
+		{"value with an equals sign", []string{"-p", "filter=a=b", "ingest"}, http.StatusAccepted,
+			"ingest", scheduler.Params{"filter": "a=b"}, 0},
+		{"repeated param", []string{"-p", "region=eu", "-p", "region=us", "ingest"}, http.StatusAccepted,
+			"ingest", scheduler.Params{"region": "us"}, 0},
+		{"escaped id", []string{"reports/daily&weekly"}, http.StatusAccepted,
+			"reports/daily&weekly", scheduler.Params{}, 0},
+		{"unknown task", []string{"missing"}, http.StatusNotFound,
+			"missing", scheduler.Params{}, 1},
+
+		{"no task", []string{"-p", "region=eu"}, 0, "", nil, 2},
+		{"two tasks", []string{"ingest", "report"}, 0, "", nil, 2},
+		{"param without a value", []string{"-p", "region", "ingest"}, 0, "", nil, 2},
+		{"param without a name", []string{"-p", "=eu", "ingest"}, 0, "", nil, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				requests int
+				id       string
+				params   scheduler.Params
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				requests++
+				if request.Method != http.MethodPost || request.URL.Path != "/tasks/trigger" {
+					t.Errorf("expected a POST to /tasks/trigger, got a %s to %s", request.Method, request.URL.Path)
+				}
+				if contentType := request.Header.Get("Content-Type"); contentType != "application/json" {
+					t.Errorf("expected a JSON body, got %s", contentType)
+				}
+				id = request.URL.Query().Get("id")
+				if err := json.NewDecoder(request.Body).Decode(&params); err != nil {
+					t.Errorf("expected the params as JSON: %v", err)
+				}
+				writer.WriteHeader(test.status)
+			}))
+			defer server.Close()
+
+			// A trailing slash on the address is dropped.
+			args := append([]string{"-addr", server.URL + "/"}, test.args...)
+			if code := trigger(args); code != test.code {
+				t.Errorf("expected exit code %d, got %d", test.code, code)
+			}
+			if test.status == 0 {
+				if requests != 0 {
+					t.Errorf("expected no request, got %d", requests)
+				}
+				return
+			}
+			if requests != 1 {
+				t.Fatalf("expected a single request, got %d", requests)
+			}
+			if id != test.id {
+				t.Errorf("expected task %q, got %q", test.id, id)
+			}
+			if !reflect.DeepEqual(params, test.params) {
+				t.Errorf("expected params %v, got %v", test.params, params)
+			}
+		})
+	}
+}
+
+func TestTriggerUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	if code := trigger([]string{"-addr", server.URL, "ingest"}); code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+}
@@ -25,8 +25,12 @@ func (e ExampleTask) Run(ctx scheduler.Context) error {
 	var ll = e.logger(ctx)
 	ll.Info("hello world", zap.String("next_run", ctx.NextRunDate().String()))
 
-	time.Sleep(10 * time.Second)
-	return nil
+	select {
+	case <-time.After(10 * time.Second):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (e ExampleTask) Schedule() scheduler.Cron {